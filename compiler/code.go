@@ -0,0 +1,158 @@
+package compiler
+
+import (
+	_ "embed"
+	"fmt"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type IdGen struct {
+	CurrNum int
+}
+
+func newIdGen() *IdGen {
+	return &IdGen{}
+}
+
+func (id *IdGen) next() string {
+	id.CurrNum++
+	return fmt.Sprint(id.CurrNum)
+}
+
+type SegmentType int
+
+const (
+	SegmentTypeStrLit SegmentType = iota
+	SegmentTypeGo
+	SegmentTypeExpr
+	SegmentTypeHtmlEscapeExpr
+	SegmentTypeUrlEscapeExpr
+)
+
+type Segment struct {
+	Type    SegmentType
+	BufName string
+	Content []byte
+}
+
+func (s Segment) empty() bool { return len(s.Content) == 0 }
+
+type Code struct {
+	PendingSegment Segment
+	Segments       []Segment
+}
+
+func newCode(buf string) Code {
+	return Code{
+		PendingSegment: Segment{
+			BufName: buf,
+		},
+	}
+}
+
+func (code *Code) emit(t SegmentType, content string) {
+	if code.PendingSegment.Type != t {
+		bufName := code.flush()
+		code.PendingSegment = Segment{Type: t, BufName: bufName}
+	}
+	code.PendingSegment.Content = append(code.PendingSegment.Content, content...)
+}
+
+func (code *Code) emitGo(content string) {
+	code.emit(SegmentTypeGo, content)
+}
+
+func (code *Code) emitStrLit(content string) {
+	code.emit(SegmentTypeStrLit, content)
+}
+
+func (code *Code) emitExpr(content string) {
+	code.emit(SegmentTypeExpr, content)
+}
+
+func (code *Code) emitHtmlEscapeExpr(content string) {
+	code.emit(SegmentTypeHtmlEscapeExpr, content)
+}
+
+func (comp *Code) emitUrlEscapeExpr(content string) {
+	comp.emit(SegmentTypeUrlEscapeExpr, content)
+}
+
+func (code *Code) emitSplit() {
+	code.flush()
+	code.PendingSegment = Segment{
+		BufName: "tx_w2",
+	}
+}
+
+func (code *Code) writeTo(codeBuilder *CodeBuilder) {
+	code.flush()
+	for _, segment := range code.Segments {
+		switch segment.Type {
+		case SegmentTypeGo:
+			codeBuilder.WriteString(string(segment.Content))
+		case SegmentTypeStrLit:
+			codeBuilder.write("%s.WriteString(%s)\n", segment.BufName, strconv.Quote(string(segment.Content)))
+		case SegmentTypeExpr:
+			codeBuilder.write("fmt.Fprint(%s, %s)\n", segment.BufName, string(segment.Content))
+		case SegmentTypeHtmlEscapeExpr:
+			codeBuilder.write("%s.WriteString(html.EscapeString(fmt.Sprint(%s)))\n", segment.BufName, string(segment.Content))
+		case SegmentTypeUrlEscapeExpr:
+			codeBuilder.write("if param, err := json.Marshal(%s); err != nil {\nlog.Panic(err)\n} else {\n%s.WriteString(url.QueryEscape(string(param)))}\n", string(segment.Content), segment.BufName)
+		}
+	}
+}
+
+// userCode marks code, Go copied from the component's file at p, so that
+// errors the type checker finds in it can be reported against the file. The
+// marks are delimited by NUL bytes, which Go source cannot contain; generate
+// strips them from the output and turns them into line directives for the
+// type checker.
+func (comp *Component) userCode(p token.Pos, code string) string {
+	mark := comp.lineMark(p)
+	if mark == "" {
+		return code
+	}
+	return mark + code + "\x00\x00"
+}
+
+// lineMark returns the mark that makes the code after it start at p, or ""
+// when p is unknown.
+func (comp *Component) lineMark(p token.Pos) string {
+	pos := comp.src.position(p)
+	if !pos.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("\x00%s:%d:%d\x00", comp.lineFile, pos.Line, pos.Column)
+}
+
+// userCodeMark matches the marks added by userCode. The one after the code
+// has an empty position.
+var userCodeMark = regexp.MustCompile("\x00([^\x00]*)\x00")
+
+func (code *Code) flush() string {
+	bufName := code.PendingSegment.BufName
+	if !code.PendingSegment.empty() {
+		code.Segments = append(code.Segments, code.PendingSegment)
+	}
+	code.PendingSegment = Segment{}
+	return bufName
+}
+
+//go:embed runtime.js
+var runtimeScript string
+
+type CodeBuilder struct {
+	strings.Builder
+}
+
+func (code *CodeBuilder) write(s string, params ...any) {
+	if len(params) == 0 {
+		code.WriteString(s)
+	} else {
+		fmt.Fprintf(&code.Builder, s, params...)
+	}
+}
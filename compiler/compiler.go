@@ -0,0 +1,904 @@
+// Package compiler turns tmplx pages and components into Go source that
+// serves them with net/http.
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"io/fs"
+	"maps"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Config describes where a project's sources live and what to generate.
+// Directories are slash-separated paths inside the fs.FS given to Compile.
+type Config struct {
+	// PagesDirs are the roots of the page routes. Each must exist, and
+	// every page in them is served at its path relative to its root, so
+	// two roots cannot hold pages with the same route. A _layout.html file
+	// wraps the pages in its directory and below, whichever root they are
+	// in.
+	PagesDirs []string
+	// ComponentsDirs are searched for components, which are named after
+	// their path relative to their directory. Missing directories are
+	// skipped, and two directories cannot hold components with the same
+	// name.
+	ComponentsDirs []string
+
+	// OutputFile is the path the generated source will be written to. It is
+	// only used to resolve imports relative to the output package and to
+	// find the package to type-check.
+	OutputFile string
+
+	// OutputDir, when set, replaces OutputFile with a directory that gets
+	// one file per page and component plus routes.go, which lists their
+	// routes. Like OutputFile, it is only used to find the output package.
+	OutputDir string
+
+	PackageName   string
+	HandlerPrefix string
+
+	// StateEncoding is how pages embed state: StateEncodingJSON, the
+	// default, with the variable names as keys, or StateEncodingCompact,
+	// with short keys and large state compressed.
+	StateEncoding string
+
+	// SourceDir is the directory the fs.FS given to Compile reads, if it
+	// reads one. When set, the output file gets //line directives that make
+	// Go compile errors and panics in code copied from pages and components
+	// point at their files.
+	SourceDir string
+
+	// TypeCheck resolves the imports of tmplx scripts and type-checks the
+	// generated code together with the rest of the output package, which
+	// must be inside a Go module, and reports errors against the pages and
+	// components they came from. It runs the go command.
+	TypeCheck bool
+}
+
+const (
+	StateEncodingJSON    = "json"
+	StateEncodingCompact = "compact"
+)
+
+// Result is the outcome of a compilation.
+type Result struct {
+	// Source is the formatted Go source, nil when compilation failed or
+	// OutputDir is set.
+	Source []byte
+	// Files maps the names of the files to write to OutputDir to their
+	// formatted Go source when OutputDir is set, and is nil otherwise.
+	// Other files in the output package that satisfy IsGenerated are left
+	// over from earlier builds.
+	Files      map[string][]byte
+	Pages      int
+	Components int
+	// Diagnostics are the problems found. Compilation fails when one of
+	// them is an error rather than a warning.
+	Diagnostics []Diagnostic
+}
+
+// Compile compiles every page and component in fsys. When the project has
+// errors, the returned error is an ErrorList holding Result.Diagnostics.
+func Compile(cfg Config, fsys fs.FS) (Result, error) {
+	return New(cfg, fsys).Build()
+}
+
+// Compiler compiles a project and keeps the compiled pages and components
+// between builds, so Rebuild can recompile a changed file and its dependents
+// only.
+type Compiler struct {
+	cfg  Config
+	fsys fs.FS
+
+	componentsByName map[string]*Component
+	pages            []*Component
+	layouts          []*Component
+	components       []*Component
+	files            []outputFile
+	ok               bool
+
+	// packageNames maps the import paths found by resolveImports to the
+	// names of their packages.
+	packageNames map[string]string
+}
+
+func New(cfg Config, fsys fs.FS) *Compiler {
+	if cfg.PackageName == "" {
+		cfg.PackageName = "main"
+	}
+	if cfg.HandlerPrefix == "" {
+		cfg.HandlerPrefix = "/tx/"
+	}
+	cfg.PagesDirs = cleanPaths(cfg.PagesDirs)
+	cfg.ComponentsDirs = cleanPaths(cfg.ComponentsDirs)
+
+	return &Compiler{
+		cfg:              cfg,
+		fsys:             fsys,
+		componentsByName: map[string]*Component{},
+		packageNames:     map[string]string{},
+	}
+}
+
+// Build registers every page and component from scratch and compiles them.
+func (c *Compiler) Build() (Result, error) {
+	return c.result(c.build())
+}
+
+// Rebuild recompiles the pages and components behind the changed paths,
+// plus everything that renders them. Paths are slash-separated paths inside
+// the compiler's fs.FS. Added or removed files change the set of known
+// component tags, so they fall back to a full build, as does any Rebuild
+// after a failed build.
+func (c *Compiler) Rebuild(paths []string) (Result, error) {
+	return c.result(c.rebuild(paths))
+}
+
+func (c *Compiler) result(merr *multiError) (Result, error) {
+	res := Result{
+		Pages:       len(c.pages),
+		Components:  len(c.components),
+		Diagnostics: merr.diagnostics(),
+	}
+	if slices.ContainsFunc(res.Diagnostics, func(d Diagnostic) bool { return d.Severity == SeverityError }) {
+		return res, ErrorList(res.Diagnostics)
+	}
+	if c.cfg.OutputDir == "" {
+		res.Source = c.files[0].source
+		return res, nil
+	}
+	res.Files = map[string][]byte{}
+	for _, file := range c.files {
+		res.Files[file.name] = file.source
+	}
+	return res, nil
+}
+
+// sources returns every component, layout and page.
+func (c *Compiler) sources() []*Component {
+	return slices.Concat(c.components, c.layouts, c.pages)
+}
+
+// outputDir returns the directory of the output package.
+func (c *Compiler) outputDir() string {
+	if c.cfg.OutputDir != "" {
+		return c.cfg.OutputDir
+	}
+	return filepath.Dir(c.cfg.OutputFile)
+}
+
+func (c *Compiler) build() *multiError {
+	c.ok = false
+	merr := c.register()
+	if !merr.empty() {
+		return merr
+	}
+
+	merr.concat(c.load(c.sources()))
+	if !merr.empty() {
+		return merr
+	}
+
+	return c.compile(c.sources())
+}
+
+func (c *Compiler) rebuild(paths []string) *multiError {
+	if !c.ok {
+		return c.build()
+	}
+
+	changed := []*Component{}
+	for _, p := range paths {
+		comp := c.componentByPath(p)
+		_, err := fs.Stat(c.fsys, p)
+		exists := err == nil
+		if comp == nil {
+			if exists || c.hasFilesUnder(p) {
+				return c.build()
+			}
+			continue
+		}
+		if !exists {
+			return c.build()
+		}
+		changed = append(changed, comp)
+	}
+	if len(changed) == 0 {
+		return newMultiError()
+	}
+
+	c.ok = false
+	merr := c.load(changed)
+	if !merr.empty() {
+		return merr
+	}
+
+	return c.compile(c.dependents(changed))
+}
+
+func (c *Compiler) componentByPath(filePath string) *Component {
+	for _, comp := range c.sources() {
+		if comp.FilePath == filePath {
+			return comp
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) hasFilesUnder(dir string) bool {
+	for _, comp := range c.sources() {
+		if strings.HasPrefix(comp.FilePath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// dependents returns comps together with every component and page that
+// renders one of them, directly or through other components.
+func (c *Compiler) dependents(comps []*Component) []*Component {
+	dirty := map[*Component]struct{}{}
+	queue := slices.Clone(comps)
+	for len(queue) > 0 {
+		comp := queue[0]
+		queue = queue[1:]
+		if _, ok := dirty[comp]; ok {
+			continue
+		}
+		dirty[comp] = struct{}{}
+		for _, parent := range c.sources() {
+			if _, ok := parent.ChildComps[comp.Name]; ok {
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	return slices.DeleteFunc(c.sources(), func(comp *Component) bool {
+		_, ok := dirty[comp]
+		return !ok
+	})
+}
+
+// 1. register component and page HTML files
+func (c *Compiler) register() *multiError {
+	c.componentsByName = map[string]*Component{}
+	c.components = nil
+	c.layouts = nil
+	c.pages = nil
+
+	merr := newMultiError()
+	for _, componentsDir := range c.cfg.ComponentsDirs {
+		if exist, err := dirExist(c.fsys, componentsDir); err != nil {
+			merr.append(Diagnostic{File: componentsDir, Code: CodeFileAccess, Message: fmt.Sprintf("cannot access components directory: %v", err)})
+			continue
+
+		} else if !exist {
+			// components are optional
+
+		} else if err := fs.WalkDir(c.fsys, componentsDir, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				merr.append(Diagnostic{File: filePath, Code: CodeFileAccess, Message: fmt.Sprintf("cannot access: %v", err)})
+				return nil
+			}
+
+			if entry.IsDir() {
+				return nil
+			}
+
+			if path.Ext(filePath) != ".html" {
+				return nil
+			}
+
+			relPath := strings.TrimPrefix(filePath, componentsDir+"/")
+			stemPath, _ := strings.CutSuffix(relPath, ".html")
+			if stemPath == "" {
+				merr.append(Diagnostic{File: filePath, Code: CodeFileName, Message: "invalid filename: .html (missing name before extension)"})
+				return nil
+			}
+			name := "tx-" + strings.ReplaceAll(stemPath, "/", "-")
+			for _, r := range name {
+				if !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_') {
+					merr.append(Diagnostic{File: filePath, Code: CodeFileName, Message: fmt.Sprintf("invalid character \"%s\" in <%s>: use only a-z, 0-9, -, _", string(r), name)})
+					return nil
+				}
+			}
+
+			if comp, ok := c.componentsByName[name]; ok {
+				merr.append(Diagnostic{File: filePath, Code: CodeDuplicateComponent, Message: fmt.Sprintf("duplicate component <%s>, first defined in %s", name, comp.FilePath)})
+				return nil
+			}
+
+			c.componentsByName[name] = &Component{
+				compiler: c,
+				Type:     CompTypeComp,
+				FilePath: filePath,
+				RelPath:  relPath,
+				lineFile: c.lineFile(filePath),
+				Name:     name,
+				GoName:   goIdent(name),
+			}
+
+			return nil
+
+		}); err != nil {
+			merr.append(Diagnostic{File: componentsDir, Code: CodeFileAccess, Message: fmt.Sprintf("walk failed: %v", err)})
+		}
+	}
+
+	pageFiles := map[string]string{}
+	layoutsByDir := map[string]*Component{}
+	for _, pagesDir := range c.cfg.PagesDirs {
+		if exist, err := dirExist(c.fsys, pagesDir); err != nil {
+			merr.append(Diagnostic{File: pagesDir, Code: CodeFileAccess, Message: fmt.Sprintf("cannot access pages directory: %v", err)})
+			continue
+
+		} else if !exist {
+			merr.append(Diagnostic{File: pagesDir, Code: CodeFileAccess, Message: "pages directory not found"})
+			continue
+
+		} else if err := fs.WalkDir(c.fsys, pagesDir, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				merr.append(Diagnostic{File: filePath, Code: CodeFileAccess, Message: fmt.Sprintf("cannot access: %v", err)})
+				return nil
+			}
+
+			if entry.IsDir() {
+				return nil
+			}
+
+			if path.Ext(filePath) != ".html" {
+				return nil
+			}
+
+			relPath := strings.TrimPrefix(filePath, pagesDir+"/")
+
+			urlDir, _ := strings.CutSuffix(relPath, entry.Name())
+			if entry.Name() == layoutFileName {
+				dir := norm.NFC.String("/" + urlDir)
+				if layout, ok := layoutsByDir[dir]; ok {
+					merr.append(Diagnostic{File: filePath, Code: CodeDuplicateLayout, Message: fmt.Sprintf("duplicate layout for %s, first defined in %s", dir, layout.FilePath)})
+					return nil
+				}
+
+				name := dir + "_layout"
+				layout := &Component{
+					compiler: c,
+					Type:     CompTypeLayout,
+					FilePath: filePath,
+					RelPath:  relPath,
+					lineFile: c.lineFile(filePath),
+					Name:     name,
+					GoName:   goIdent(name),
+				}
+				layoutsByDir[dir] = layout
+				c.layouts = append(c.layouts, layout)
+				return nil
+			}
+
+			errorStatus, isErrorPage := errorPageFiles[entry.Name()]
+			if isErrorPage && urlDir != "" {
+				merr.append(Diagnostic{File: filePath, Code: CodeFileName, Message: fmt.Sprintf("%s must be at the root of a pages directory", entry.Name())})
+				return nil
+			}
+
+			baseName, _ := strings.CutSuffix(entry.Name(), ".html")
+			if baseName == "" {
+				merr.append(Diagnostic{File: filePath, Code: CodeFileName, Message: "invalid filename: .html (missing name before extension)"})
+				return nil
+			}
+
+			urlPath := "/" + urlDir
+			if baseName != "index" {
+				urlPath += baseName
+			}
+			urlPath = norm.NFC.String(urlPath)
+
+			if strings.HasSuffix(urlPath, "/") {
+				urlPath += "{$}"
+			}
+
+			if existingFile, ok := pageFiles[urlPath]; ok {
+				merr.append(Diagnostic{File: filePath, Code: CodeDuplicateRoute, Message: fmt.Sprintf("duplicate page route %s, first defined in %s", urlPath, existingFile)})
+				return nil
+			}
+
+			pageFiles[urlPath] = filePath
+			c.pages = append(c.pages, &Component{
+				compiler: c,
+				Type:     CompTypePage,
+				FilePath: filePath,
+				RelPath:  relPath,
+				lineFile: c.lineFile(filePath),
+				Name:     urlPath,
+				GoName:   goIdent(urlPath),
+
+				ErrorStatus: errorStatus,
+			})
+
+			return nil
+
+		}); err != nil {
+			merr.append(Diagnostic{File: pagesDir, Code: CodeFileAccess, Message: fmt.Sprintf("walk failed: %v", err)})
+		}
+	}
+
+	c.components = slices.SortedFunc(maps.Values(c.componentsByName), func(a, b *Component) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	slices.SortFunc(c.layouts, func(a, b *Component) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, layout := range c.layouts {
+		layout.Layout = closestLayout(layoutsByDir, urlParentDir(strings.TrimSuffix(layout.Name, "_layout")))
+	}
+	for _, page := range c.pages {
+		page.Layout = closestLayout(layoutsByDir, norm.NFC.String("/"+strings.TrimSuffix(page.RelPath, path.Base(page.RelPath))))
+	}
+
+	return merr
+}
+
+// layoutFileName is the name of the layout files in pages directories.
+const layoutFileName = "_layout.html"
+
+// notFoundFileName and errorFileName are the pages served for unknown URLs
+// and for handlers that panic.
+const (
+	notFoundFileName = "_404.html"
+	errorFileName    = "_error.html"
+)
+
+// errorPageFiles maps the file names of error pages to their status codes.
+var errorPageFiles = map[string]int{
+	notFoundFileName: http.StatusNotFound,
+	errorFileName:    http.StatusInternalServerError,
+}
+
+// closestLayout returns the layout of dir, a URL path ending in a slash, or
+// of the closest directory above it.
+func closestLayout(layoutsByDir map[string]*Component, dir string) *Component {
+	for dir != "" {
+		if layout, ok := layoutsByDir[dir]; ok {
+			return layout
+		}
+		dir = urlParentDir(dir)
+	}
+	return nil
+}
+
+// urlParentDir returns the directory above dir, a URL path ending in a slash,
+// or "" for the root.
+func urlParentDir(dir string) string {
+	if dir == "/" {
+		return ""
+	}
+	parent := path.Dir(strings.TrimSuffix(dir, "/"))
+	if parent == "/" {
+		return parent
+	}
+	return parent + "/"
+}
+
+// 2. parse component and page script and slot
+func (c *Compiler) load(comps []*Component) *multiError {
+	merr := newMultiError()
+	var wg sync.WaitGroup
+	for _, comp := range comps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			merr.concat(comp.load())
+		}()
+	}
+	wg.Wait()
+	return merr
+}
+
+func (comp *Component) load() *multiError {
+	comp.src = nil
+	comp.TmplxScriptNode = nil
+	comp.StyleNode = nil
+	comp.HeadNode = nil
+	comp.Slots = nil
+	comp.InitFunc = nil
+
+	switch {
+	case comp.isDocument():
+		return comp.loadPage()
+	case comp.Type == CompTypePage:
+		return comp.loadWrappedPage()
+	default:
+		return comp.loadComponent()
+	}
+}
+
+func (comp *Component) loadComponent() *multiError {
+	merr := newMultiError()
+	data, err := fs.ReadFile(comp.compiler.fsys, comp.FilePath)
+	if err != nil {
+		merr.append(comp.errf(srcRange{}, CodeFileAccess, "cannot open file: %w", err))
+		return merr
+	}
+
+	nodes, src, err := parseHTML(comp.FilePath, data, true)
+	comp.src = src
+	if err != nil {
+		merr.append(comp.errf(srcRange{}, CodeInvalidHTML, "invalid HTML: %w", err))
+		return merr
+	}
+
+	comp.TemplateNode = newTemplateNode()
+	for _, node := range nodes {
+		val, found := hasAttr(node, "type")
+		if node.DataAtom == atom.Script && found && val == "text/tmplx" {
+			if comp.TmplxScriptNode != nil {
+				merr.append(comp.errf(comp.src.nodeRange(node), CodeDuplicateElement, "multiple <script type=\"text/tmplx\"> elements (only one allowed)"))
+				return merr
+			}
+			comp.TmplxScriptNode = node
+		} else if node.DataAtom == atom.Style && comp.Type == CompTypeComp {
+			if comp.StyleNode != nil {
+				merr.append(comp.errf(comp.src.nodeRange(node), CodeDuplicateElement, "multiple <style> elements (only one allowed)"))
+				return merr
+			}
+			comp.StyleNode = node
+		} else {
+			comp.TemplateNode.AppendChild(node)
+		}
+	}
+
+	merr.concat(comp.parseTmplxScript())
+	merr.concat(comp.parseSlots(comp.TemplateNode, false))
+	if comp.Type == CompTypeLayout {
+		merr.concat(comp.checkLayoutSlots())
+	}
+	return merr
+}
+
+func (page *Component) loadPage() *multiError {
+	merr := newMultiError()
+	data, err := fs.ReadFile(page.compiler.fsys, page.FilePath)
+	if err != nil {
+		merr.append(page.errf(srcRange{}, CodeFileAccess, "cannot open file: %w", err))
+		return merr
+	}
+
+	nodes, src, err := parseHTML(page.FilePath, data, false)
+	page.src = src
+	if err != nil {
+		merr.append(page.errf(srcRange{}, CodeInvalidHTML, "invalid HTML: %w", err))
+		return merr
+	}
+	page.TemplateNode = nodes[0]
+
+	txSavedNode := &html.Node{
+		Type:     html.ElementNode,
+		DataAtom: atom.Script,
+		Data:     "script",
+		Attr: []html.Attribute{
+			{Key: "type", Val: "application/json"},
+			{Key: "id", Val: "tx-saved"},
+		},
+	}
+
+	var foundScript bool
+	var head *html.Node
+	for node := range page.TemplateNode.Descendants() {
+		if !foundScript && isTmplxScriptNode(node) {
+			page.TmplxScriptNode = node
+			foundScript = true
+		}
+		if head == nil && node.DataAtom == atom.Head {
+			node.AppendChild(txSavedNode)
+			node.AppendChild(&html.Node{
+				Type:     html.ElementNode,
+				DataAtom: atom.Script,
+				Data:     "script",
+				Attr: []html.Attribute{
+					{Key: "id", Val: "tx-runtime"},
+				},
+			})
+			head = node
+		}
+		if foundScript && head != nil {
+			break
+		}
+	}
+	if head == nil {
+		merr.append(page.errf(srcRange{}, CodeMissingHead, "%s must have a <head> element (required for state and runtime script injection)", page.Type))
+		return merr
+	}
+
+	cleanUpTmplxScript(page.TemplateNode)
+
+	merr.concat(page.parseTmplxScript())
+	if page.Type == CompTypeLayout {
+		for node := range page.TemplateNode.ChildNodes() {
+			merr.concat(page.parseSlots(node, false))
+		}
+		merr.concat(page.checkLayoutSlots())
+
+		// The <head> content of the page goes first, so that its <title>
+		// is the one browsers use.
+		head.InsertBefore(&html.Node{
+			Type:     html.ElementNode,
+			DataAtom: atom.Slot,
+			Data:     "slot",
+			Attr:     []html.Attribute{{Key: "name", Val: headSlotName}},
+		}, head.FirstChild)
+		page.Slots = append([]string{headSlotName}, page.Slots...)
+	}
+	return merr
+}
+
+// headSlotName names the slot an outermost layout renders the <head> content
+// of its page in. Layouts cannot have named slots of their own.
+const headSlotName = "head"
+
+// loadWrappedPage loads a page rendered in a layout. The page is parsed as a
+// document, like one without a layout, but only the content of its <head>
+// and <body> is kept.
+func (page *Component) loadWrappedPage() *multiError {
+	merr := newMultiError()
+	data, err := fs.ReadFile(page.compiler.fsys, page.FilePath)
+	if err != nil {
+		merr.append(page.errf(srcRange{}, CodeFileAccess, "cannot open file: %w", err))
+		return merr
+	}
+
+	nodes, src, err := parseHTML(page.FilePath, data, false)
+	page.src = src
+	if err != nil {
+		merr.append(page.errf(srcRange{}, CodeInvalidHTML, "invalid HTML: %w", err))
+		return merr
+	}
+
+	var head, body *html.Node
+	for node := range nodes[0].Descendants() {
+		if page.TmplxScriptNode == nil && isTmplxScriptNode(node) {
+			page.TmplxScriptNode = node
+		}
+		if head == nil && node.DataAtom == atom.Head {
+			head = node
+		}
+		if body == nil && node.DataAtom == atom.Body {
+			body = node
+		}
+	}
+	cleanUpTmplxScript(nodes[0])
+
+	if head != nil && !isBlank(head) {
+		page.HeadNode = newTemplateNode()
+		moveChildren(page.HeadNode, head)
+	}
+	page.TemplateNode = newTemplateNode()
+	if body != nil {
+		moveChildren(page.TemplateNode, body)
+	}
+
+	merr.concat(page.parseTmplxScript())
+	return merr
+}
+
+// checkLayoutSlots checks that a layout has a default <slot> for the pages it
+// wraps, outside of any component.
+func (layout *Component) checkLayoutSlots() *multiError {
+	merr := newMultiError()
+	for node := range layout.TemplateNode.Descendants() {
+		if node.Type != html.ElementNode || node.DataAtom != atom.Slot {
+			continue
+		}
+		if name, _ := hasAttr(node, "name"); name != "" {
+			merr.append(layout.errf(layout.src.nodeRange(node), CodeLayoutSlot, "<slot name=\"%s\"> in a layout (layouts only have a default <slot> for their pages)", name))
+		}
+		for parent := node.Parent; parent != nil; parent = parent.Parent {
+			if _, ok := layout.compiler.componentsByName[parent.Data]; ok {
+				merr.append(layout.errf(layout.src.nodeRange(node), CodeLayoutSlot, "the <slot> of a layout cannot be inside <%s>", parent.Data))
+				break
+			}
+		}
+	}
+	if !slices.Contains(layout.Slots, "") {
+		merr.append(layout.errf(srcRange{}, CodeLayoutSlot, "layout has no <slot> to render its pages in"))
+	}
+	return merr
+}
+
+// isBlank reports whether node only holds whitespace.
+func isBlank(node *html.Node) bool {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.TextNode || strings.TrimSpace(c.Data) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// moveChildren moves the children of from to the end of to.
+func moveChildren(to, from *html.Node) {
+	for c := from.FirstChild; c != nil; c = from.FirstChild {
+		from.RemoveChild(c)
+		to.AppendChild(c)
+	}
+}
+
+// compile runs the template stages for dirty and generates the output source.
+// Components outside dirty keep the results of the previous build.
+func (c *Compiler) compile(dirty []*Component) *multiError {
+	merr := newMultiError()
+	for _, comp := range dirty {
+		clear(comp.src.copies)
+		comp.HasChildComps = false
+		comp.ChildComps = map[string]struct{}{}
+		comp.Fills = nil
+		comp.AnonFuncs = nil
+		comp.links = nil
+	}
+	for _, comp := range c.components {
+		comp.CompFills = slices.DeleteFunc(comp.CompFills, func(fill *Fill) bool {
+			return slices.Contains(dirty, fill.ParentComp)
+		})
+	}
+
+	// 3. parse used vars has child comps
+	var wg sync.WaitGroup
+	for _, comp := range dirty {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			comp.ChildCompsIdGen = map[string]*IdGen{}
+			for _, child := range c.components {
+				comp.ChildCompsIdGen[child.Name] = newIdGen()
+			}
+			comp.UsedVars = map[string]struct{}{}
+			comp.FillByGoName = map[string]*Fill{}
+			merr.concat(comp.parseUsedVars(comp.TemplateNode))
+
+			comp.HeadFill = nil
+			if comp.HeadNode != nil {
+				hasChildComps, usedVars := comp.HasChildComps, comp.UsedVars
+				comp.HasChildComps = false
+				comp.UsedVars = map[string]struct{}{}
+				merr.concat(comp.parseUsedVars(comp.HeadNode))
+				comp.HeadFill = &Fill{
+					GoName:     comp.GoName + "_head",
+					ParentComp: comp,
+
+					HasChildComps: comp.HasChildComps,
+					UsedVars:      comp.UsedVars,
+				}
+				comp.Fills = append(comp.Fills, comp.HeadFill)
+				comp.HasChildComps = hasChildComps || comp.HasChildComps
+				comp.UsedVars = usedVars
+				maps.Copy(comp.UsedVars, comp.HeadFill.UsedVars)
+			}
+
+			for _, v := range comp.Vars {
+				if v.Type == VarTypeDerived {
+					comp.scanVarRefs(v.InitExprAst, comp.UsedInGo)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if !merr.empty() {
+		return merr
+	}
+	for _, comp := range c.components {
+		slices.SortFunc(comp.CompFills, func(a, b *Fill) int {
+			return strings.Compare(a.GoName, b.GoName)
+		})
+		comp.CompFillsHasChildComps = false
+		for _, fill := range comp.CompFills {
+			if fill.HasChildComps {
+				comp.CompFillsHasChildComps = true
+				break
+			}
+		}
+	}
+
+	// 4. parse pages and components template
+	for _, comp := range dirty {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			comp.ChildCompsIdGen = map[string]*IdGen{}
+			for _, child := range c.components {
+				comp.ChildCompsIdGen[child.Name] = newIdGen()
+			}
+			comp.AnonFuncNameGen = newIdGen()
+
+			switch comp.Type {
+			case CompTypeComp:
+				comp.RenderFunc = newCode("tx_w")
+				comp.RenderFunc.emitStrLit("<!--tx:")
+				comp.RenderFunc.emitExpr("tx_id")
+				comp.RenderFunc.emitStrLit("-->")
+				merr.concat(comp.parseTmpl(comp.TemplateNode, []string{}, false))
+				comp.RenderFunc.emitStrLit("<!--tx:")
+				comp.RenderFunc.emitExpr("tx_id + \"_e\"")
+				comp.RenderFunc.emitStrLit("-->")
+			case CompTypePage, CompTypeLayout:
+				if comp.isDocument() {
+					comp.RenderFunc = newCode("tx_w1")
+				} else {
+					comp.RenderFunc = newCode("tx_w")
+				}
+				merr.concat(comp.parseTmpl(comp.TemplateNode, []string{}, false))
+			}
+			if comp.HeadFill != nil {
+				renderFunc := comp.RenderFunc
+				comp.RenderFunc = newCode("tx_w")
+				merr.concat(comp.parseTmpl(comp.HeadNode, []string{}, false))
+				comp.HeadFill.RenderFunc = comp.RenderFunc
+				comp.RenderFunc = renderFunc
+			}
+		}()
+	}
+	wg.Wait()
+	for _, comp := range c.sources() {
+		for _, v := range comp.Vars {
+			_, used := comp.UsedVars[v.GoName]
+			_, ingo := comp.UsedInGo[v.GoName]
+			if !used && !ingo {
+				merr.append(comp.errf(srcRange{pos: v.Pos, end: v.Pos + token.Pos(len(v.GoName))}, CodeUnusedVar, "%s declared but not used", v.GoName))
+			}
+		}
+	}
+	merr.concat(c.checkImports())
+	if !merr.empty() {
+		return merr
+	}
+	merr.concat(c.setURLFuncs())
+	merr.concat(c.checkLinks())
+	merr.concat(c.setRouteMiddleware())
+
+	// 5. generate the output Go files
+	files, err := c.generate()
+	if err != nil {
+		merr.append(Diagnostic{Code: CodeGenerate, Message: err.Error()})
+		return merr
+	}
+
+	c.files = files
+	c.ok = true
+
+	// 6. type-check the output package
+	if c.cfg.TypeCheck {
+		merr.concat(c.typeCheck(files))
+	}
+	return merr
+}
+
+func cleanPaths(paths []string) []string {
+	cleaned := make([]string, len(paths))
+	for i, p := range paths {
+		cleaned[i] = path.Clean(p)
+	}
+	return cleaned
+}
+
+func dirExist(fsys fs.FS, name string) (bool, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return info.IsDir(), nil
+}
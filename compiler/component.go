@@ -0,0 +1,2022 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+type CompType int
+
+const (
+	CompTypeComp CompType = iota
+	CompTypePage
+	CompTypeLayout
+)
+
+func (t CompType) String() string {
+	switch t {
+	case CompTypePage:
+		return "page"
+	case CompTypeLayout:
+		return "layout"
+	default:
+		return "component"
+	}
+}
+
+type Component struct {
+	compiler *Compiler
+	src      *source
+	// lineFile is how line directives in the output file refer to the file.
+	lineFile string
+
+	Type     CompType
+	FilePath string
+	RelPath  string
+	Name     string
+	GoName   string
+	// Layout is the closest _layout.html above a page or layout, or nil.
+	Layout *Component
+	// ErrorStatus is the status code _404.html and _error.html pages are
+	// served with, and 0 for other pages.
+	ErrorStatus int
+	// Middleware are the //tx:middleware functions that wrap the routes of a
+	// page, or of every page in a layout.
+	Middleware []string
+	// RouteMiddleware are the middleware that wrap the event routes of a
+	// component, those of routeMiddlewarePage and the other pages that
+	// render it share.
+	RouteMiddleware     []string
+	routeMiddlewarePage *Component
+	// URLFunc is the name of the generated function that builds the URL of
+	// a page, or empty when it has none.
+	URLFunc string
+	// links are the literal <a href> values of the template, checked
+	// against the page routes.
+	links []link
+
+	TmplxScriptNode *html.Node
+	TemplateNode    *html.Node
+	StyleNode       *html.Node
+	// HeadNode holds the <head> content of a page rendered in a layout,
+	// which goes to the start of the outermost layout's <head>.
+	HeadNode *html.Node
+	Slots    []string
+
+	Imports    []*ast.ImportSpec
+	Vars       []*Var
+	VarByName  map[string]*Var
+	InitFunc   *Func
+	Funcs      []*Func
+	FuncByName map[string]*Func
+
+	ChildCompsIdGen        map[string]*IdGen
+	ChildComps             map[string]struct{}
+	HasChildComps          bool
+	UsedVars               map[string]struct{}
+	UsedInGo               map[string]struct{}
+	UsedPkgs               map[string]struct{}
+	Fills                  []*Fill
+	FillByGoName           map[string]*Fill
+	CompFills              []*Fill
+	CompFillsMu            sync.Mutex
+	CompFillsHasChildComps bool
+
+	AnonFuncNameGen *IdGen
+	AnonFuncs       []*Func
+	RenderFunc      Code
+	// HeadFill renders HeadNode.
+	HeadFill *Fill
+}
+
+// layouts returns the layouts that wrap comp, outermost first.
+func (comp *Component) layouts() []*Component {
+	var layouts []*Component
+	for l := comp.Layout; l != nil; l = l.Layout {
+		layouts = append(layouts, l)
+	}
+	slices.Reverse(layouts)
+	return layouts
+}
+
+// funcRoute returns the expression for the route of the handler name in
+// comp's render function. Pages and components get their routes as
+// parameters. A layout handles events on every page it wraps, so its routes
+// start with tx_route, which is set for the page being rendered.
+func (comp *Component) funcRoute(name string) string {
+	if comp.Type == CompTypeLayout {
+		return "tx_route + " + strconv.Quote(name)
+	}
+	return name
+}
+
+// isDocument reports whether comp renders a whole document: a page outside
+// any layout, or an outermost layout.
+func (comp *Component) isDocument() bool {
+	return comp.Type != CompTypeComp && comp.Layout == nil
+}
+
+// link is a literal href and where it appears.
+type link struct {
+	href string
+	at   srcRange
+}
+
+// errf reports a problem in r, a range of the component's file that is zero
+// when the problem is with the file as a whole.
+func (comp *Component) errf(r srcRange, code DiagnosticCode, msg string, a ...any) error {
+	d := Diagnostic{File: comp.FilePath, Code: code, Message: fmt.Errorf(msg, a...).Error()}
+	if start := comp.src.position(r.pos); start.IsValid() {
+		d.Range.Start = Position{Line: start.Line, Column: start.Column}
+		d.Range.End = d.Range.Start
+		if end := comp.src.position(r.end); end.IsValid() {
+			d.Range.End = Position{Line: end.Line, Column: end.Column}
+		}
+	}
+	return d
+}
+
+// warnf is errf for problems that do not fail the build.
+func (comp *Component) warnf(r srcRange, code DiagnosticCode, msg string, a ...any) error {
+	d := comp.errf(r, code, msg, a...).(Diagnostic)
+	d.Severity = SeverityWarning
+	return d
+}
+
+func (comp *Component) parseSlots(node *html.Node, inSlot bool) *multiError {
+	merr := newMultiError()
+	if node.Type != html.ElementNode {
+		return nil
+	}
+
+	isSlot := node.DataAtom == atom.Slot
+	if isSlot {
+		if inSlot {
+			merr.append(comp.errf(comp.src.nodeRange(node), CodeNestedSlot, "<slot> cannot be nested inside another <slot>"))
+		}
+
+		slotName := ""
+		if name, found := hasAttr(node, "name"); found {
+			slotName = name
+		}
+
+		if !slices.Contains(comp.Slots, slotName) {
+			comp.Slots = append(comp.Slots, slotName)
+		} else {
+			if slotName == "" {
+				merr.append(comp.errf(comp.src.nodeRange(node), CodeDuplicateSlot, "duplicate default <slot> (only one allowed)"))
+			} else {
+				merr.append(comp.errf(comp.src.nodeRange(node), CodeDuplicateSlot, "duplicate <slot name=\"%s\"> (only one allowed)", slotName))
+			}
+		}
+	}
+
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		merr.concat(comp.parseSlots(c, isSlot))
+	}
+
+	return merr
+}
+
+func (comp *Component) parseTmplxScript() *multiError {
+	merr := newMultiError()
+
+	comp.Imports = []*ast.ImportSpec{}
+	comp.Vars = []*Var{}
+	comp.VarByName = map[string]*Var{}
+	comp.UsedInGo = map[string]struct{}{}
+	comp.UsedPkgs = map[string]struct{}{}
+	comp.Funcs = []*Func{}
+	comp.FuncByName = map[string]*Func{}
+	comp.Middleware = nil
+
+	if comp.TmplxScriptNode != nil {
+		text := comp.TmplxScriptNode.FirstChild
+		comp.src.setScript(text)
+		fset := token.NewFileSet()
+		scriptAst, err := parser.ParseFile(fset, "", scriptPrefix+text.Data, parser.ParseComments)
+		if err != nil {
+			off, msg := syntaxError(err)
+			merr.append(comp.errf(point(comp.src.scriptOffsetPos(off)), CodeScriptSyntax, "syntax error in <script type=\"text/tmplx\">: %s", msg))
+			return merr
+		}
+		comp.src.script = fset.File(scriptAst.FileStart)
+		at := comp.src.scriptRange
+
+		for _, group := range scriptAst.Comments {
+			for _, comment := range group.List {
+				for _, directive := range parseComments(comment.Text) {
+					if directive.Name != CommentMiddleware {
+						continue
+					}
+					if comp.Type == CompTypeComp {
+						merr.append(comp.errf(at(comment), CodeInvalidDirective, "//tx:middleware: components cannot have middleware"))
+						continue
+					}
+					if directive.Value == "" {
+						merr.append(comp.errf(at(comment), CodeInvalidDirective, "//tx:middleware: missing function name"))
+						continue
+					}
+					for _, name := range strings.Fields(directive.Value) {
+						expr, err := parser.ParseExpr(name)
+						if err != nil || !isNamedType(expr) {
+							merr.append(comp.errf(at(comment), CodeInvalidDirective, "//tx:middleware %s: must be a function name", name))
+							continue
+						}
+						comp.scanPkgRefs(expr)
+						comp.Middleware = append(comp.Middleware, comp.userCode(at(comment).pos, name))
+					}
+				}
+			}
+		}
+
+		allVarNames := map[string]struct{}{}
+		for _, decl := range scriptAst.Decls {
+			if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.VAR && len(d.Specs) > 0 {
+				if s, ok := d.Specs[0].(*ast.ValueSpec); ok && len(s.Names) > 0 {
+					allVarNames[s.Names[0].Name] = struct{}{}
+				}
+			}
+			if d, ok := decl.(*ast.GenDecl); !ok || d.Tok != token.IMPORT {
+				comp.scanPkgRefs(decl)
+			}
+		}
+
+		for _, decl := range scriptAst.Decls {
+			switch d := decl.(type) {
+			case *ast.BadDecl:
+				merr.append(comp.errf(at(decl), CodeInvalidDecl, "invalid declaration: %s", astToSource(decl)))
+			case *ast.GenDecl:
+				switch d.Tok {
+				case token.IMPORT:
+					for _, spec := range d.Specs {
+						s, ok := spec.(*ast.ImportSpec)
+						if !ok {
+							merr.append(comp.errf(at(spec), CodeInvalidDecl, "invalid import: %s", astToSource(spec)))
+							continue
+						}
+
+						comp.Imports = append(comp.Imports, s)
+					}
+				case token.VAR:
+					if len(d.Specs) == 0 {
+						continue
+					}
+					if len(d.Specs) > 1 {
+						merr.append(comp.errf(at(d), CodeMultipleVars, "declare one variable per var statement: %s", astToSource(d)))
+						continue
+					}
+
+					spec := d.Specs[0]
+					s, ok := spec.(*ast.ValueSpec)
+					if !ok {
+						merr.append(comp.errf(at(spec), CodeInvalidDecl, "invalid variable declaration: %s", astToSource(spec)))
+						continue
+					}
+
+					if s.Type == nil {
+						merr.append(comp.errf(at(spec), CodeMissingType, "missing type annotation: %s", astToSource(spec)))
+					}
+
+					if len(s.Names) > 1 {
+						merr.append(comp.errf(at(s.Names[1]), CodeMultipleVars, "declare one variable per var statement: %s", astToSource(spec)))
+						continue
+					}
+
+					ident := s.Names[0]
+					if strings.HasPrefix(ident.Name, "tx_") {
+						merr.append(comp.errf(at(ident), CodeReservedName, "%s: variable name cannot start with tx_ (reserved prefix)", ident.Name))
+						continue
+					}
+					if ident.Name == responseName {
+						merr.append(comp.errf(at(ident), CodeReservedName, "%s: variable name is reserved for the response", ident.Name))
+						continue
+					}
+
+					newVar := &Var{
+						Pos:        at(ident).pos,
+						GoName:     ident.Name,
+						SavedField: "S_" + ident.Name,
+						TypeExpr:   astToSource(s.Type),
+					}
+					if s.Type != nil {
+						newVar.TypePos = at(s.Type).pos
+					}
+
+					isProp := false
+					isPath := false
+					isError := false
+					directives := []CommentName{}
+					if d.Doc != nil {
+						comments := []Comment{}
+						for _, comment := range d.Doc.List {
+							comments = append(comments, parseComments(comment.Text)...)
+						}
+
+						for _, comment := range comments {
+							if comment.Name == CommentMiddleware {
+								continue
+							}
+							if comment.Name == CommentPrivate {
+								newVar.Private = true
+								continue
+							}
+							if !slices.Contains(directives, comment.Name) {
+								directives = append(directives, comment.Name)
+							}
+							switch comment.Name {
+							case CommentQuery, CommentHeader, CommentCookie, CommentURL:
+								newVar.Binding = &comment
+							case CommentProp:
+								isProp = true
+							case CommentError:
+								isError = true
+								errAst := &ast.CallExpr{
+									Fun: &ast.SelectorExpr{
+										X:   &ast.Ident{Name: "tx_err"},
+										Sel: &ast.Ident{Name: "Error"},
+									},
+								}
+								newVar.InitExprAst = errAst
+								newVar.InitExpr = astToSource(errAst)
+							case CommentPath:
+								isPath = true
+								newVar.PathParam = comment.Value
+								pathAst := &ast.CallExpr{
+									Fun: &ast.SelectorExpr{
+										X:   &ast.Ident{Name: "tx_r"},
+										Sel: &ast.Ident{Name: "PathValue"},
+									},
+									Args: []ast.Expr{
+										&ast.BasicLit{
+											Value: fmt.Sprintf("\"%s\"", comment.Value),
+											Kind:  token.STRING,
+										},
+									},
+								}
+								newVar.InitExprAst = pathAst
+								newVar.InitExpr = astToSource(pathAst)
+							}
+						}
+					}
+
+					if len(directives) > 1 {
+						merr.append(comp.errf(at(ident), CodeInvalidDirective, "cannot combine //tx:%s and //tx:%s on %s", directives[0], directives[1], ident.Name))
+					} else if isProp {
+						if comp.Type != CompTypeComp {
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:prop on %s: %ss cannot have props", ident.Name, comp.Type))
+						}
+						if len(s.Values) == 1 {
+							newVar.InitExprAst = s.Values[0]
+							newVar.InitExpr = comp.userCode(at(s.Values[0]).pos, astToSource(s.Values[0]))
+						}
+						newVar.Type = VarTypeProp
+
+					} else if isPath {
+						if len(s.Values) > 0 {
+							merr.append(comp.errf(at(s.Values[0]), CodeInvalidDirective, "//tx:path variable cannot have an initial value: %s", astToSource(spec)))
+						}
+						switch {
+						case comp.Type == CompTypeComp:
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:path on %s: components cannot have path variables", ident.Name))
+						case newVar.PathParam == "":
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:path on %s: missing wildcard name", ident.Name))
+						case !slices.Contains(routeWildcards(comp.Name), newVar.PathParam):
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:path %s: %s has no {%s} wildcard", newVar.PathParam, strings.TrimSuffix(comp.Name, "_layout"), newVar.PathParam))
+						}
+						switch {
+						case s.Type == nil:
+						case !isNamedType(s.Type):
+							merr.append(comp.errf(at(s.Type), CodeInvalidDirective, "//tx:path variable must be a string, integer or encoding.TextUnmarshaler type: %s", astToSource(spec)))
+						case comp.Type == CompTypeLayout && astToSource(s.Type) != "string":
+							merr.append(comp.errf(at(s.Type), CodeInvalidDirective, "//tx:path variable of a layout must be type string: %s", astToSource(spec)))
+						}
+						newVar.Type = VarTypeState
+
+					} else if isError {
+						if len(s.Values) > 0 {
+							merr.append(comp.errf(at(s.Values[0]), CodeInvalidDirective, "//tx:error variable cannot have an initial value: %s", astToSource(spec)))
+						}
+						if comp.ErrorStatus == 0 {
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:error on %s: only %s and %s have an error", ident.Name, notFoundFileName, errorFileName))
+						}
+						if s.Type != nil && astToSource(s.Type) != "string" {
+							merr.append(comp.errf(at(s.Type), CodeInvalidDirective, "//tx:error variable must be type string: %s", astToSource(spec)))
+						}
+						newVar.Type = VarTypeState
+
+					} else if len(s.Values) == 1 || len(s.Values) == 0 {
+						found := false
+						if len(s.Values) == 1 {
+							v := s.Values[0]
+							astutil.Apply(v, func(c *astutil.Cursor) bool {
+								id, ok := c.Node().(*ast.Ident)
+								if !ok {
+									return true
+								}
+								if !atVarRefPos(c) {
+									return false
+								}
+								if _, inAll := allVarNames[id.Name]; inAll {
+									if _, inDeclared := comp.VarByName[id.Name]; !inDeclared {
+										merr.append(comp.errf(at(id), CodeUseBeforeDecl, "%s: variable %s used before declaration", s.Names[0].Name, id.Name))
+									}
+									found = true
+								}
+								return false
+							}, nil)
+							newVar.InitExprAst = v
+							// The positions in cloned are offsets into v as
+							// printed, which is how v is written in most cases.
+							cloned, _ := parser.ParseExpr(astToSource(v))
+							start := at(v).pos
+							newVar.InitExpr = comp.userCode(start, astToSource(comp.rewriteVarRefs(cloned, func(p token.Pos) token.Pos {
+								return start + p - 1
+							})))
+						}
+
+						if found {
+							newVar.Type = VarTypeDerived
+						} else {
+							newVar.Type = VarTypeState
+						}
+
+					} else if len(s.Values) > 1 {
+						merr.append(comp.errf(at(s.Values[1]), CodeMultipleVars, "declare one variable per var statement: %s", astToSource(spec)))
+					}
+
+					if newVar.Private && newVar.Type == VarTypeDerived {
+						merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:private on %s: derived variables are not kept in the client", ident.Name))
+					}
+
+					if b := newVar.Binding; b != nil && len(directives) == 1 {
+						switch {
+						case b.Name == CommentURL && comp.Type != CompTypePage:
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:url on %s: only pages can keep state in the URL", ident.Name))
+						case b.Name == CommentURL && b.Value != "" && b.Value != urlPush:
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:url on %s: unknown option %q (available: %s)", ident.Name, b.Value, urlPush))
+						case comp.Type == CompTypeComp:
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:%s on %s: components cannot read the request", b.Name, ident.Name))
+						case b.Value == "" && b.Name != CommentURL:
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:%s on %s: missing %s name", b.Name, ident.Name, b.Name))
+						case newVar.Type == VarTypeDerived:
+							merr.append(comp.errf(at(ident), CodeInvalidDirective, "//tx:%s on %s: derived variables cannot be set from the request", b.Name, ident.Name))
+						}
+					}
+
+					comp.Vars = append(comp.Vars, newVar)
+					comp.VarByName[ident.Name] = newVar
+				}
+			}
+		}
+
+		for _, decl := range scriptAst.Decls {
+			d, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+
+			if d.Recv != nil {
+				merr.append(comp.errf(at(d.Recv), CodeInvalidFunc, "%s: methods (func with receiver) not allowed, use plain functions", d.Name))
+			}
+
+			if d.Type.Results != nil {
+				merr.append(comp.errf(at(d.Type.Results), CodeInvalidFunc, "%s: return values not allowed", d.Name))
+			}
+
+			for _, field := range d.Type.Params.List {
+				for _, name := range field.Names {
+					if strings.HasPrefix(name.Name, "tx_") {
+						merr.append(comp.errf(at(name), CodeReservedName, "%s: parameter %s cannot start with tx_ (reserved prefix)", d.Name, name.Name))
+					} else if name.Name == responseName {
+						merr.append(comp.errf(at(name), CodeReservedName, "%s: parameter %s is reserved for the response", d.Name, name.Name))
+					}
+					if comp.VarByName[name.Name] != nil {
+						merr.append(comp.errf(at(name), CodeShadow, "%s: parameter %s shadows a state variable", d.Name, name.Name))
+					}
+				}
+			}
+
+			if d.Body != nil {
+				for _, ident := range comp.readOnlyMutations(d.Body) {
+					merr.append(comp.errf(at(ident), CodeReadOnlyAssign, "%s: cannot assign to %s (derived/prop is read-only)", d.Name, ident.Name))
+				}
+				for _, ident := range comp.shadowingLocals(d.Body) {
+					merr.append(comp.errf(at(ident), CodeShadow, "%s: local variable %s shadows a state/prop/derived variable (rename the local)", d.Name, ident.Name))
+				}
+				comp.scanVarRefs(d.Body, comp.UsedInGo)
+			}
+
+			if strings.HasPrefix(d.Name.Name, "tx_") {
+				merr.append(comp.errf(at(d.Name), CodeReservedName, "%s: function name cannot start with tx_ (reserved prefix)", d.Name.Name))
+				continue
+			}
+			if d.Name.Name == responseName {
+				merr.append(comp.errf(at(d.Name), CodeReservedName, "%s: function name is reserved for the response", d.Name.Name))
+				continue
+			}
+
+			newFunc := &Func{
+				Name: d.Name.Name,
+				Decl: d,
+			}
+			// A component function without a body is passed by the parent
+			// as a prop and has no statements of its own.
+			if d.Body == nil {
+				if comp.Type != CompTypeComp || d.Name.Name == "init" {
+					merr.append(comp.errf(at(d.Name), CodeInvalidFunc, "%s: missing function body", d.Name.Name))
+					continue
+				}
+				comp.FuncByName[d.Name.Name] = newFunc
+				comp.Funcs = append(comp.Funcs, newFunc)
+				continue
+			}
+			dirtyDerived := comp.dirtyDerivedNames(d.Body)
+			var b strings.Builder
+			for _, stmt := range d.Body.List {
+				b.WriteString(comp.userCode(at(stmt).pos, astToSource(comp.rewriteVarRefs(stmt, comp.src.scriptPos))))
+				b.WriteByte('\n')
+			}
+			for _, name := range dirtyDerived {
+				v := comp.VarByName[name]
+				fmt.Fprintf(&b, "tx_derived_%s = %s\n", v.GoName, v.InitExpr)
+			}
+			newFunc.Stmts = b.String()
+
+			if d.Name.Name == "init" {
+				comp.InitFunc = newFunc
+			} else {
+				comp.FuncByName[d.Name.Name] = newFunc
+				comp.Funcs = append(comp.Funcs, newFunc)
+			}
+		}
+	}
+
+	return merr
+}
+
+func atVarRefPos(c *astutil.Cursor) bool {
+	switch c.Name() {
+	case "Sel", "Names":
+		return false
+	case "Key":
+		if _, ok := c.Parent().(*ast.KeyValueExpr); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// rewriteVarRefs renames references to the component's variables to the
+// names they have in generated code. When at is set, every identifier is
+// marked with its position in the file, converted by at, so that the
+// positions of type errors stay accurate after renaming.
+func (comp *Component) rewriteVarRefs(node ast.Node, at func(token.Pos) token.Pos) ast.Node {
+	return astutil.Apply(node, func(c *astutil.Cursor) bool {
+		ident, ok := c.Node().(*ast.Ident)
+		if !ok {
+			return true
+		}
+		name := ident.Name
+		if v, ok := comp.VarByName[ident.Name]; ok && atVarRefPos(c) {
+			switch v.Type {
+			case VarTypeState, VarTypeProp:
+				name = "tx_saved." + v.SavedField
+			case VarTypeDerived:
+				name = "tx_derived_" + v.GoName
+			}
+		}
+		if at != nil {
+			if name != ident.Name {
+				name += comp.lineMark(at(ident.End()))
+			}
+			name = comp.lineMark(at(ident.Pos())) + name
+		}
+		if name != ident.Name {
+			c.Replace(&ast.Ident{NamePos: ident.NamePos, Name: name})
+		}
+		return false
+	}, nil)
+}
+
+func (comp *Component) readOnlyMutations(node ast.Node) []*ast.Ident {
+	seen := map[string]struct{}{}
+	var result []*ast.Ident
+	check := func(expr ast.Expr) {
+		var ident *ast.Ident
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if ident != nil {
+				return false
+			}
+			if id, ok := n.(*ast.Ident); ok {
+				ident = id
+				return false
+			}
+			return true
+		})
+		if ident == nil {
+			return
+		}
+		v, ok := comp.VarByName[ident.Name]
+		if !ok {
+			return
+		}
+		if _, dup := seen[v.GoName]; dup {
+			return
+		}
+		if v.Type == VarTypeDerived || v.Type == VarTypeProp {
+			seen[v.GoName] = struct{}{}
+			result = append(result, ident)
+		}
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch stmt := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range stmt.Lhs {
+				check(lhs)
+			}
+		case *ast.IncDecStmt:
+			check(stmt.X)
+		}
+		return true
+	})
+	return result
+}
+
+func (comp *Component) shadowingLocals(body ast.Node) []*ast.Ident {
+	var found []*ast.Ident
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch stmt := n.(type) {
+		case *ast.AssignStmt:
+			if stmt.Tok != token.DEFINE {
+				return true
+			}
+			for _, lhs := range stmt.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if ident.Name == "_" {
+					continue
+				}
+				if _, ok := comp.VarByName[ident.Name]; ok {
+					found = append(found, ident)
+				}
+			}
+		case *ast.RangeStmt:
+			if stmt.Tok != token.DEFINE {
+				return true
+			}
+			for _, expr := range []ast.Expr{stmt.Key, stmt.Value} {
+				if expr == nil {
+					continue
+				}
+				ident, ok := expr.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if ident.Name == "_" {
+					continue
+				}
+				if _, ok := comp.VarByName[ident.Name]; ok {
+					found = append(found, ident)
+				}
+			}
+		case *ast.DeclStmt:
+			genDecl, ok := stmt.Decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.VAR {
+				return true
+			}
+			for _, spec := range genDecl.Specs {
+				valSpec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for _, name := range valSpec.Names {
+					if name.Name == "_" {
+						continue
+					}
+					if _, ok := comp.VarByName[name.Name]; ok {
+						found = append(found, name)
+					}
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+func (comp *Component) dirtyDerivedNames(body *ast.BlockStmt) []string {
+	dirty := map[string]struct{}{}
+	markBase := func(lhs ast.Expr) {
+		var ident *ast.Ident
+		ast.Inspect(lhs, func(n ast.Node) bool {
+			if ident != nil {
+				return false
+			}
+			if id, ok := n.(*ast.Ident); ok {
+				ident = id
+				return false
+			}
+			return true
+		})
+		if ident == nil {
+			return
+		}
+		if v, ok := comp.VarByName[ident.Name]; ok {
+			if v.Type == VarTypeState || v.Type == VarTypeProp {
+				dirty[ident.Name] = struct{}{}
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch stmt := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range stmt.Lhs {
+				markBase(lhs)
+			}
+		case *ast.IncDecStmt:
+			markBase(stmt.X)
+		}
+		return true
+	})
+	result := []string{}
+	for _, v := range comp.Vars {
+		if v.Type != VarTypeDerived {
+			continue
+		}
+		needsRecalc := false
+		astutil.Apply(v.InitExprAst, func(c *astutil.Cursor) bool {
+			if needsRecalc {
+				return false
+			}
+			id, ok := c.Node().(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if !atVarRefPos(c) {
+				return false
+			}
+			if _, ok := dirty[id.Name]; ok {
+				needsRecalc = true
+			}
+			return false
+		}, nil)
+		if needsRecalc {
+			result = append(result, v.GoName)
+			dirty[v.GoName] = struct{}{}
+		}
+	}
+	return result
+}
+
+func (comp *Component) parseUsedVars(node *html.Node) *multiError {
+	merr := newMultiError()
+	switch node.Type {
+	case html.DocumentNode:
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			merr.concat(comp.parseUsedVars(c))
+		}
+		return merr
+
+	case html.TextNode:
+		_, hasTxIgnore := hasAttr(node.Parent, "tx-ignore")
+		// script/style bodies are JS/CSS where { is not template syntax, so skip interpolation.
+		isScriptOrStyle := node.Parent.DataAtom == atom.Script || node.Parent.DataAtom == atom.Style
+		if hasTxIgnore || isScriptOrStyle {
+			return nil
+		}
+
+		comp.parseUsedVarsStr(node.Data)
+
+	case html.ElementNode:
+		if childComp, ok := comp.compiler.componentsByName[node.Data]; ok {
+			comp.HasChildComps = true
+			comp.ChildComps[childComp.Name] = struct{}{}
+			idNum := comp.ChildCompsIdGen[childComp.Name].next()
+
+			for _, v := range childComp.Vars {
+				if v.Type == VarTypeProp {
+					if val, found := hasAttr(node, v.GoName); found {
+						if propExpr, perr := parser.ParseExpr(val); perr == nil {
+							comp.markUsedVars(propExpr)
+						}
+					}
+				}
+			}
+
+			fillNodes := comp.parseFillNodes(node)
+			for _, slotName := range childComp.Slots {
+				if n, ok := fillNodes[slotName]; ok {
+					savedHasChildComps := comp.HasChildComps
+					savedUsedVars := comp.UsedVars
+
+					comp.HasChildComps = false
+					comp.UsedVars = map[string]struct{}{}
+
+					merr.concat(comp.parseUsedVars(n))
+
+					fillHasChildComps := comp.HasChildComps
+					fillUsedVars := comp.UsedVars
+
+					comp.HasChildComps = savedHasChildComps || fillHasChildComps
+					comp.UsedVars = savedUsedVars
+					for v := range fillUsedVars {
+						comp.UsedVars[v] = struct{}{}
+					}
+
+					fillGoName := fmt.Sprintf("%s_%s_%s_%s", comp.GoName, childComp.GoName, idNum, goIdent(slotName))
+					newFill := &Fill{
+						GoName:     fillGoName,
+						CompName:   childComp.Name,
+						ParentComp: comp,
+						Location:   fmt.Sprintf("%s_%s_%s", comp.Name, idNum, slotName),
+
+						HasChildComps: fillHasChildComps,
+						UsedVars:      fillUsedVars,
+						RenderFunc:    newCode("tx_w"),
+					}
+					comp.Fills = append(comp.Fills, newFill)
+					comp.FillByGoName[fillGoName] = newFill
+					childComp.CompFillsMu.Lock()
+					childComp.CompFills = append(childComp.CompFills, newFill)
+					childComp.CompFillsMu.Unlock()
+				}
+			}
+			return merr
+
+		} else if node.DataAtom == atom.Slot {
+			for c := node.FirstChild; c != nil; c = c.NextSibling {
+				merr.concat(comp.parseUsedVars(c))
+			}
+			return merr
+
+		} else if node.DataAtom != atom.Template {
+			if _, isIgnored := hasAttr(node, "tx-ignore"); !isIgnored {
+				for _, attr := range node.Attr {
+					if attr.Key == "tx-if" || attr.Key == "tx-else-if" || attr.Key == "tx-else" || attr.Key == "tx-for" {
+						continue
+					}
+					if strings.HasPrefix(attr.Key, "tx-on") || attr.Key == "tx-action" {
+						continue
+					}
+					comp.parseUsedVarsStr(attr.Val)
+				}
+			}
+		}
+
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				if _, field := condState(c); field != "" {
+					if fieldExpr, ferr := parser.ParseExpr(field); ferr == nil {
+						comp.markUsedVars(fieldExpr)
+					}
+				}
+
+				if stmt, ok := hasAttr(c, "tx-for"); ok {
+					if forAst, ferr := parser.ParseFile(token.NewFileSet(), "", "package p\nfunc f(){for "+stmt+"{}}", 0); ferr == nil {
+						comp.markUsedVars(forAst)
+					}
+				}
+
+				if key, ok := hasAttr(c, "tx-key"); ok {
+					if keyExpr, kerr := parser.ParseExpr(key); kerr == nil {
+						comp.markUsedVars(keyExpr)
+					}
+				}
+			}
+
+			merr.concat(comp.parseUsedVars(c))
+		}
+
+		return merr
+	}
+
+	return nil
+}
+
+func (comp *Component) scanVarRefs(node ast.Node, target map[string]struct{}) {
+	comp.scanPkgRefs(node)
+	astutil.Apply(node, func(c *astutil.Cursor) bool {
+		id, ok := c.Node().(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if !atVarRefPos(c) {
+			return false
+		}
+		if _, ok := comp.VarByName[id.Name]; ok {
+			target[id.Name] = struct{}{}
+		}
+		return false
+	}, nil)
+}
+
+// scanPkgRefs records the identifiers node selects from, which include the
+// names of the packages it uses.
+func (comp *Component) scanPkgRefs(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				comp.UsedPkgs[id.Name] = struct{}{}
+			}
+		}
+		return true
+	})
+}
+
+func (comp *Component) markUsedVars(node ast.Node) {
+	comp.scanVarRefs(node, comp.UsedVars)
+}
+
+func (comp *Component) parseTmpl(node *html.Node, forKeys []string, inSlot bool) *multiError {
+	merr := newMultiError()
+	switch node.Type {
+	case html.DoctypeNode:
+		comp.RenderFunc.emitStrLit(fmt.Sprintf("<!DOCTYPE %s>", node.Data))
+		return nil
+	case html.CommentNode:
+		comp.RenderFunc.emitStrLit(fmt.Sprintf("<!--%s-->", node.Data))
+		return nil
+	case html.DocumentNode:
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			merr.concat(comp.parseTmpl(c, forKeys, inSlot))
+		}
+		return merr
+	case html.TextNode:
+		_, hasTxIgnore := hasAttr(node.Parent, "tx-ignore")
+		// script/style bodies are JS/CSS where { is not template syntax, so skip interpolation.
+		isScriptOrStyle := node.Parent.DataAtom == atom.Script || node.Parent.DataAtom == atom.Style
+		isRawText := isChildNodeRawText(node.Parent.Data)
+
+		if hasTxIgnore || isScriptOrStyle {
+			if isRawText {
+				comp.RenderFunc.emitStrLit(node.Data)
+			} else {
+				comp.RenderFunc.emitStrLit(html.EscapeString(node.Data))
+			}
+			return nil
+		}
+
+		return newMultiError(comp.parseTmplStr(node.Data, !isRawText, !isRawText, func(i int) token.Pos {
+			return comp.src.textPos(node, i)
+		}))
+	case html.ElementNode:
+		if childComp, ok := comp.compiler.componentsByName[node.Data]; ok {
+			comp.HasChildComps = true
+			idNum := comp.ChildCompsIdGen[childComp.Name].next()
+			id := fmt.Sprintf("%s-%s", childComp.Name, idNum)
+
+			for _, attr := range node.Attr {
+				if attr.Key == "tx-if" || attr.Key == "tx-else-if" || attr.Key == "tx-else" || attr.Key == "tx-for" || attr.Key == "tx-key" || attr.Key == "slot" {
+					continue
+				}
+				if _, ok := childComp.FuncByName[attr.Key]; ok {
+					continue
+				}
+
+				v, ok := childComp.VarByName[attr.Key]
+				if !ok {
+					merr.append(comp.errf(comp.src.attrRange(node, attr.Key), CodeUnknownProp, "<%s %s=\"...\">: %s is not a prop or function in component %s", node.Data, attr.Key, attr.Key, childComp.Name))
+					continue
+				}
+
+				if v.Type != VarTypeProp {
+					merr.append(comp.errf(comp.src.attrRange(node, attr.Key), CodeNotProp, "<%s %s=\"...\">: %s is a state variable, not a prop; use //tx:prop to declare it as a prop", node.Data, attr.Key, attr.Key))
+					continue
+				}
+
+				parentVar, ok := comp.VarByName[attr.Val]
+				if !ok {
+					continue
+				}
+
+				propType := v.TypeExpr
+				parentType := parentVar.TypeExpr
+				if propType != parentType {
+					merr.append(comp.errf(comp.src.attrRange(node, attr.Key), CodePropType, "<%s %s=\"%s\">: type mismatch; prop %s expects %s but got %s", node.Data, attr.Key, attr.Val, attr.Key, propType, parentType))
+					continue
+				}
+			}
+
+			comp.RenderFunc.emitGo("{\n")
+
+			comp.RenderFunc.emitGo("tx_cid := ")
+			if inSlot {
+				comp.RenderFunc.emitGo("tx_id")
+				for _, key := range forKeys {
+					comp.RenderFunc.emitGo(fmt.Sprintf(" + \":\" + fmt.Sprint(%s)", key))
+				}
+				comp.RenderFunc.emitGo(fmt.Sprintf(" + \"@%s\"", id))
+			} else {
+				switch comp.Type {
+				case CompTypePage:
+					if len(forKeys) == 0 {
+						comp.RenderFunc.emitGo(fmt.Sprintf("\"%s\"", id))
+					} else {
+						comp.RenderFunc.emitGo(fmt.Sprintf("fmt.Sprint(%s)", forKeys[0]))
+						for _, key := range forKeys[1:] {
+							comp.RenderFunc.emitGo(fmt.Sprintf(" + \":\" + fmt.Sprint(%s)", key))
+						}
+						comp.RenderFunc.emitGo(fmt.Sprintf(" + \":%s\"", id))
+					}
+				case CompTypeComp, CompTypeLayout:
+					comp.RenderFunc.emitGo("tx_id")
+					for _, key := range forKeys {
+						comp.RenderFunc.emitGo(fmt.Sprintf(" + \":\" + fmt.Sprint(%s)", key))
+					}
+					comp.RenderFunc.emitGo(fmt.Sprintf(" + \":%s\"", id))
+				}
+			}
+			comp.RenderFunc.emitGo("\n")
+
+			if len(childComp.Vars) == 0 {
+				comp.RenderFunc.emitGo(fmt.Sprintf("tx_next_saved[tx_cid] = &%s{}\n", childComp.GoName))
+			} else {
+				comp.RenderFunc.emitGo(fmt.Sprintf("tx_saved := &%s{}\n", childComp.GoName))
+				for _, v := range childComp.Vars {
+					if v.Type == VarTypeDerived {
+						comp.RenderFunc.emitGo(fmt.Sprintf("var tx_derived_%s %s\n", v.GoName, childComp.userCode(v.TypePos, v.TypeExpr)))
+					}
+				}
+
+				comp.RenderFunc.emitGo("tx_curr_saved_str, tx_curr_saved_exist := tx_curr_saved[tx_cid]\n")
+				comp.RenderFunc.emitGo("if tx_curr_saved_exist {\n")
+				comp.RenderFunc.emitGo("tx.unmarshalState(tx_cid, tx_curr_saved_str, tx_saved)\n")
+				for _, v := range childComp.Vars {
+					if v.Type == VarTypeProp {
+						if val, found := hasAttr(node, v.GoName); found {
+							comp.RenderFunc.emitGo(fmt.Sprintf("tx_saved.%s = %s\n", v.SavedField, comp.userCode(comp.src.attrValPos(node, v.GoName, 0), val)))
+						}
+					}
+				}
+				for _, v := range childComp.Vars {
+					if v.Type == VarTypeDerived {
+						comp.RenderFunc.emitGo(fmt.Sprintf("tx_derived_%s = %s\n", v.GoName, v.InitExpr))
+					}
+				}
+
+				hasElseContent := childComp.InitFunc != nil
+				for _, v := range childComp.Vars {
+					if v.Type == VarTypeDerived {
+						hasElseContent = true
+					} else if v.Type == VarTypeState && v.InitExpr != "" {
+						hasElseContent = true
+					} else if v.Type == VarTypeProp {
+						if _, found := hasAttr(node, v.GoName); found || v.InitExpr != "" {
+							hasElseContent = true
+						}
+					}
+				}
+
+				if hasElseContent {
+					comp.RenderFunc.emitGo("} else {\n")
+					for _, v := range childComp.Vars {
+						switch v.Type {
+						case VarTypeState:
+							if v.InitExpr != "" {
+								comp.RenderFunc.emitGo(fmt.Sprintf("tx_saved.%s = %s\n", v.SavedField, v.InitExpr))
+							}
+						case VarTypeProp:
+							if val, found := hasAttr(node, v.GoName); found {
+								comp.RenderFunc.emitGo(fmt.Sprintf("tx_saved.%s = %s\n", v.SavedField, comp.userCode(comp.src.attrValPos(node, v.GoName, 0), val)))
+							} else if v.InitExpr != "" {
+								comp.RenderFunc.emitGo(fmt.Sprintf("tx_saved.%s = %s\n", v.SavedField, v.InitExpr))
+							}
+						case VarTypeDerived:
+							comp.RenderFunc.emitGo(fmt.Sprintf("tx_derived_%s = %s\n", v.GoName, v.InitExpr))
+						}
+					}
+
+					if childComp.InitFunc != nil {
+						comp.RenderFunc.emitGo(childComp.InitFunc.Stmts)
+					}
+				}
+				comp.RenderFunc.emitGo("}\n")
+				comp.RenderFunc.emitGo("tx_next_saved[tx_cid] = tx_saved\n")
+			}
+
+			comp.RenderFunc.emitGo(fmt.Sprintf("render_%s(%s, tx_cid", childComp.GoName, comp.RenderFunc.PendingSegment.BufName))
+
+			if len(childComp.Slots) > 0 {
+				parent := "\"page\""
+				if comp.Type != CompTypePage {
+					parent = "tx_id"
+					for _, key := range forKeys {
+						parent += ` + ":" + fmt.Sprint(` + key + `)`
+					}
+				}
+				comp.RenderFunc.emitGo(fmt.Sprintf(", %s, \"%s_%s\"", parent, comp.Name, idNum))
+			}
+			if childComp.HasChildComps {
+				comp.RenderFunc.emitGo(", tx_curr_saved, tx_next_saved, tx")
+			}
+
+			for _, v := range childComp.Vars {
+				if _, ok := childComp.UsedVars[v.GoName]; !ok {
+					continue
+				}
+				switch v.Type {
+				case VarTypeProp, VarTypeState:
+					comp.RenderFunc.emitGo(fmt.Sprintf(", tx_saved.%s", v.SavedField))
+				case VarTypeDerived:
+					comp.RenderFunc.emitGo(fmt.Sprintf(", tx_derived_%s", v.GoName))
+				}
+			}
+
+			for _, f := range childComp.Funcs {
+				if val, found := hasAttr(node, f.Name); found {
+					// Page and layout events re-render the whole page, so
+					// they have no tx-swap.
+					if pf, ok := comp.FuncByName[val]; ok && comp.Type != CompTypeComp {
+						comp.RenderFunc.emitGo(fmt.Sprintf(", %s, \"\"", comp.funcRoute(pf.Name)))
+					} else if ok {
+						comp.RenderFunc.emitGo(fmt.Sprintf(", %s, %s_swap", pf.Name, pf.Name))
+					} else {
+						merr.append(comp.errf(comp.src.attrValRange(node, f.Name), CodeUndefinedFunc, "undefined function: %s", val))
+					}
+				} else {
+					if f.Decl.Body == nil {
+						merr.append(comp.errf(comp.src.nodeRange(node), CodeMissingFuncProp, "function %s has no body in %s and must be passed as a prop", f.Name, childComp.Name))
+					} else {
+						comp.RenderFunc.emitGo(fmt.Sprintf(",\"%s:%s\", tx_cid", childComp.Name, f.Name))
+					}
+				}
+			}
+
+			fillNodes := comp.parseFillNodes(node)
+			if len(childComp.Slots) > 0 {
+				comp.RenderFunc.emitGo(",\n")
+				for _, slotName := range childComp.Slots {
+					if n, ok := fillNodes[slotName]; ok {
+						savedRenderFunc := comp.RenderFunc
+						comp.RenderFunc = newCode("tx_w")
+
+						merr.concat(comp.parseTmpl(n, forKeys, true))
+
+						currFillRenderFunc := comp.RenderFunc
+						comp.RenderFunc = savedRenderFunc
+
+						fill := comp.FillByGoName[fmt.Sprintf("%s_%s_%s_%s", comp.GoName, childComp.GoName, idNum, goIdent(slotName))]
+						fill.RenderFunc = currFillRenderFunc
+						comp.RenderFunc.emitGo(fmt.Sprintf("func () { render_fill_%s(%s", fill.GoName, comp.RenderFunc.PendingSegment.BufName))
+						if fill.HasChildComps {
+							comp.RenderFunc.emitGo(", tx_cid, tx_curr_saved, tx_next_saved, tx")
+						}
+						for _, v := range comp.Vars {
+							if _, ok := fill.UsedVars[v.GoName]; ok {
+								comp.RenderFunc.emitGo(fmt.Sprintf(", %s", v.GoName))
+							}
+						}
+						comp.RenderFunc.emitGo(") },\n")
+					} else {
+						comp.RenderFunc.emitGo("nil,\n")
+					}
+				}
+			}
+			comp.RenderFunc.emitGo(")\n")
+			comp.RenderFunc.emitGo("}\n")
+			return merr
+		} else if node.DataAtom == atom.Slot {
+			val, _ := hasAttr(node, "name")
+			comp.RenderFunc.emitGo(fmt.Sprintf("if tx_render_fill_%s != nil {\n", val))
+			comp.RenderFunc.emitGo(fmt.Sprintf("tx_render_fill_%s()\n", val))
+			if node.FirstChild != nil {
+				comp.RenderFunc.emitGo("} else {\n")
+				child := newTemplateNode()
+				for c := node.FirstChild; c != nil; c = c.NextSibling {
+					child.AppendChild(comp.src.copyNode(c))
+				}
+				merr.concat(comp.parseTmpl(child, forKeys, false))
+			}
+			comp.RenderFunc.emitGo("}\n")
+			return merr
+
+		} else if node.DataAtom != atom.Template {
+			comp.RenderFunc.emitStrLit("<")
+			comp.RenderFunc.emitStrLit(node.Data)
+
+			_, isIgnore := hasAttr(node, "tx-ignore")
+
+			for _, attr := range node.Attr {
+				if attr.Key == "tx-if" || attr.Key == "tx-else-if" || attr.Key == "tx-else" || attr.Key == "tx-for" {
+					continue
+				}
+
+				comp.RenderFunc.emitStrLit(" ")
+				if strings.HasPrefix(attr.Key, "tx-on") {
+					comp.RenderFunc.emitStrLit(attr.Key)
+					comp.RenderFunc.emitStrLit(`="`)
+
+					// Check if it's a function call
+					if expr, err := parser.ParseExpr(attr.Val); err == nil {
+						if callExpr, ok := expr.(*ast.CallExpr); ok {
+							if ident, ok := callExpr.Fun.(*ast.Ident); ok {
+								if fun, ok := comp.FuncByName[ident.Name]; ok {
+									params := []string{}
+									for _, list := range fun.Decl.Type.Params.List {
+										for _, ident := range list.Names {
+											params = append(params, ident.Name)
+										}
+									}
+
+									if len(params) != len(callExpr.Args) {
+										merr.append(comp.errf(comp.src.attrValRange(node, attr.Key), CodeHandlerArgs, "wrong number of arguments: %s", astToSource(callExpr)))
+										continue
+									}
+
+									comp.RenderFunc.emitExpr(comp.funcRoute(url.PathEscape(fun.Name)))
+									comp.scanPkgRefs(callExpr)
+									for i, param := range params {
+										foundVar := false
+										ast.Inspect(callExpr.Args[i], func(n ast.Node) bool {
+											if foundVar {
+												return false
+											}
+
+											ident, ok := n.(*ast.Ident)
+											if !ok {
+												return true
+											}
+
+											if _, ok := comp.VarByName[ident.Name]; ok {
+												foundVar = true
+												return false
+											}
+
+											return true
+										})
+
+										if foundVar {
+											merr.append(comp.errf(comp.src.attrValRange(node, attr.Key), CodeHandlerArgs, "cannot pass state/derived variable as event handler argument: %s", callExpr.Args[i]))
+											continue
+										}
+
+										if i == 0 {
+											comp.RenderFunc.emitStrLit("?" + param + "=")
+										} else {
+											comp.RenderFunc.emitStrLit("&" + param + "=")
+										}
+
+										// ParseExpr positions start at 1.
+										argPos := comp.src.attrValPos(node, attr.Key, int(callExpr.Args[i].Pos())-1)
+										arg := comp.userCode(argPos, astToSource(callExpr.Args[i]))
+										comp.RenderFunc.emitUrlEscapeExpr(arg)
+									}
+									comp.RenderFunc.emitStrLit(`"`)
+
+									if comp.Type == CompTypeComp {
+										comp.RenderFunc.emitStrLit(" tx-swap=\"")
+										if comp.Type != CompTypePage {
+											comp.RenderFunc.emitExpr(fun.Name + "_swap")
+										}
+										comp.RenderFunc.emitStrLit(`"`)
+									}
+
+									if comp.Type == CompTypeComp && len(comp.Slots) > 0 {
+										comp.RenderFunc.emitStrLit(" tx-pid=\"")
+										comp.RenderFunc.emitExpr("tx_pid")
+										comp.RenderFunc.emitStrLit("\"")
+										comp.RenderFunc.emitStrLit(" tx-loc=\"")
+										comp.RenderFunc.emitExpr("tx_loc")
+										comp.RenderFunc.emitStrLit("\"")
+									}
+
+									continue
+								}
+							}
+						}
+					}
+
+					idNum := comp.AnonFuncNameGen.next()
+					funcName := fmt.Sprintf("af-%s", idNum)
+					const handlerPrefix = "package p\nfunc f() {\n"
+					fset := token.NewFileSet()
+					at := func(off int) token.Pos {
+						return comp.src.attrValPos(node, attr.Key, max(off-len(handlerPrefix), 0))
+					}
+					fileAst, err := parser.ParseFile(fset, comp.FilePath, handlerPrefix+attr.Val+"\n}", 0)
+					if err != nil {
+						off, _ := syntaxError(err)
+						merr.append(comp.errf(point(at(off)), CodeInvalidHandler, "invalid inline handler: %s", attr.Val))
+						continue
+					}
+
+					decl, ok := fileAst.Decls[0].(*ast.FuncDecl)
+					if !ok {
+						merr.append(comp.errf(comp.src.attrValRange(node, attr.Key), CodeInvalidHandler, "invalid inline handler: %s", attr.Val))
+						continue
+					}
+
+					if modified := comp.readOnlyMutations(decl); len(modified) > 0 {
+						merr.append(comp.errf(point(at(fset.Position(modified[0].Pos()).Offset)), CodeReadOnlyAssign, "cannot assign to derived/prop variable in handler: %v", modified))
+						continue
+					}
+					for _, ident := range comp.shadowingLocals(decl) {
+						merr.append(comp.errf(point(at(fset.Position(ident.Pos()).Offset)), CodeShadow, "inline handler: local variable %s shadows a state/prop/derived variable (rename the local)", ident.Name))
+					}
+					comp.scanVarRefs(decl.Body, comp.UsedInGo)
+
+					var b strings.Builder
+					dirtyDerived := comp.dirtyDerivedNames(decl.Body)
+					for _, stmt := range decl.Body.List {
+						b.WriteString(comp.userCode(at(fset.Position(stmt.Pos()).Offset), astToSource(comp.rewriteVarRefs(stmt, func(p token.Pos) token.Pos {
+							return at(fset.Position(p).Offset)
+						}))))
+						b.WriteByte('\n')
+					}
+					for _, name := range dirtyDerived {
+						v := comp.VarByName[name]
+						fmt.Fprintf(&b, "tx_derived_%s = %s\n", v.GoName, v.InitExpr)
+					}
+
+					comp.AnonFuncs = append(comp.AnonFuncs, &Func{
+						Name:  funcName,
+						Decl:  decl,
+						Stmts: b.String(),
+					})
+
+					if comp.Type == CompTypeLayout {
+						comp.RenderFunc.emitExpr(comp.funcRoute(funcName))
+					} else {
+						comp.RenderFunc.emitStrLit(url.PathEscape(comp.Name) + ":" + funcName)
+					}
+					comp.RenderFunc.emitStrLit("\"")
+
+					if comp.Type == CompTypeComp {
+						comp.RenderFunc.emitStrLit(" tx-swap=\"")
+						comp.RenderFunc.emitExpr("tx_id")
+						comp.RenderFunc.emitStrLit(`"`)
+					}
+
+					if comp.Type == CompTypeComp && len(comp.Slots) > 0 {
+						comp.RenderFunc.emitStrLit(" tx-pid=\"")
+						comp.RenderFunc.emitExpr("tx_pid")
+						comp.RenderFunc.emitStrLit("\"")
+						comp.RenderFunc.emitStrLit(" tx-loc=\"")
+						comp.RenderFunc.emitExpr("tx_loc")
+						comp.RenderFunc.emitStrLit("\"")
+					}
+
+				} else if attr.Key == "tx-action" {
+					if node.DataAtom != atom.Form {
+						merr.append(comp.errf(comp.src.attrRange(node, attr.Key), CodeActionElement, "tx-action only allowed on <form>, got <%s>", node.Data))
+						continue
+					}
+					if !token.IsIdentifier(attr.Val) {
+						merr.append(comp.errf(comp.src.attrValRange(node, attr.Key), CodeInvalidHandler, "tx-action value must be a function name, got \"%s\"", attr.Val))
+						continue
+					}
+					fun, ok := comp.FuncByName[attr.Val]
+					if !ok {
+						merr.append(comp.errf(comp.src.attrValRange(node, attr.Key), CodeUndefinedFunc, "tx-action: undefined function %s", attr.Val))
+						continue
+					}
+					comp.RenderFunc.emitStrLit("tx-action=\"")
+					comp.RenderFunc.emitExpr(comp.funcRoute(fun.Name))
+					comp.RenderFunc.emitStrLit("\"")
+					if comp.Type == CompTypeComp {
+						comp.RenderFunc.emitStrLit(" tx-swap=\"")
+						comp.RenderFunc.emitExpr(fun.Name + "_swap")
+						comp.RenderFunc.emitStrLit("\"")
+					}
+					if comp.Type == CompTypeComp && len(comp.Slots) > 0 {
+						comp.RenderFunc.emitStrLit(" tx-pid=\"")
+						comp.RenderFunc.emitExpr("tx_pid")
+						comp.RenderFunc.emitStrLit("\"")
+						comp.RenderFunc.emitStrLit(" tx-loc=\"")
+						comp.RenderFunc.emitExpr("tx_loc")
+						comp.RenderFunc.emitStrLit("\"")
+					}
+				} else {
+					if attr.Namespace != "" {
+						comp.RenderFunc.emitStrLit(node.Namespace)
+						comp.RenderFunc.emitStrLit(":")
+					}
+					comp.RenderFunc.emitStrLit(attr.Key)
+					comp.RenderFunc.emitStrLit(`="`)
+					if node.DataAtom == atom.A && attr.Key == "href" && !isIgnore && !strings.ContainsAny(attr.Val, "{}") {
+						comp.links = append(comp.links, link{href: strings.TrimSpace(attr.Val), at: comp.src.attrValRange(node, attr.Key)})
+					}
+					if isIgnore {
+						comp.RenderFunc.emitStrLit(attr.Val)
+					} else if err := comp.parseTmplStr(strings.TrimSpace(attr.Val), false, false, func(i int) token.Pos {
+						lead := len(attr.Val) - len(strings.TrimLeftFunc(attr.Val, unicode.IsSpace))
+						return comp.src.attrValPos(node, attr.Key, lead+i)
+					}); err != nil {
+						merr.append(comp.errf(comp.src.attrValRange(node, attr.Key), CodeExprSyntax, "invalid expression in attribute: %s", attr.Val))
+					}
+					comp.RenderFunc.emitStrLit(`"`)
+				}
+			}
+
+			// https://html.spec.whatwg.org/#void-elements
+			if isVoidElement(node.Data) {
+				if node.FirstChild != nil {
+					merr.append(comp.errf(comp.src.nodeRange(node), CodeVoidChildren, "void element <%s> cannot have children", node.Data))
+				}
+
+				comp.RenderFunc.emitStrLit("/>")
+				return merr
+			}
+
+			comp.RenderFunc.emitStrLit(">")
+		}
+
+		// prevCondState tracks the conditional directive on the previous sibling:
+		// 0=none, 1=if, 2=else-if, 3=else. It is used to emit a closing "}" when
+		// a conditional chain ends, and to validate that tx-else-if/tx-else only
+		// follow tx-if or tx-else-if.
+		txNodeId, _ := hasAttr(node, "id")
+		if node.DataAtom == atom.Script && txNodeId == "tx-runtime" {
+			comp.RenderFunc.emitGo(fmt.Sprintf("%s.WriteString(tx.cfg.runtimeScript)\n", comp.RenderFunc.PendingSegment.BufName))
+		} else if node.DataAtom == atom.Script && txNodeId == "tx-saved" {
+			comp.RenderFunc.emitSplit()
+		} else {
+			var prevCondState CondState
+			for c := node.FirstChild; c != nil; c = c.NextSibling {
+				hasFor := false
+				forKey := ""
+				if c.Type == html.ElementNode {
+					currCondState, field := condState(c)
+
+					switch prevCondState {
+					case CondStateDefault:
+						if currCondState == CondStateElseIf || currCondState == CondStateElse {
+							merr.append(comp.errf(comp.src.nodeRange(c), CodeElseWithoutIf, "tx-else-if/tx-else on <%s> without preceding tx-if", c.Data))
+						}
+					case CondStateIf:
+						if currCondState <= prevCondState {
+							comp.RenderFunc.emitGo("\n}\n")
+						}
+					case CondStateElseIf:
+						if currCondState < prevCondState {
+							comp.RenderFunc.emitGo("\n}\n")
+						}
+					case CondStateElse:
+						if currCondState == CondStateElseIf || currCondState == CondStateElse {
+							merr.append(comp.errf(comp.src.nodeRange(c), CodeElseAfterElse, "tx-else-if/tx-else on <%s> after tx-else (nothing can follow tx-else)", c.Data))
+						}
+						comp.RenderFunc.emitGo("\n}\n")
+					}
+
+					switch currCondState {
+					case CondStateDefault:
+					case CondStateIf:
+						comp.RenderFunc.emitGo("if " + comp.userCode(comp.src.attrValPos(c, "tx-if", 0), field) + " {\n")
+					case CondStateElseIf:
+						comp.RenderFunc.emitGo("} else if " + comp.userCode(comp.src.attrValPos(c, "tx-else-if", 0), field) + " {\n")
+					case CondStateElse:
+						comp.RenderFunc.emitGo("} else {\n")
+					}
+
+					prevCondState = currCondState
+
+					if stmt, ok := hasAttr(c, "tx-for"); ok {
+						val, found := hasAttr(c, "tx-key")
+						if !found {
+							merr.append(comp.errf(comp.src.attrRange(c, "tx-for"), CodeForWithoutKey, "tx-for requires a tx-key attribute"))
+						} else {
+							hasFor = true
+							forKey = comp.userCode(comp.src.attrValPos(c, "tx-key", 0), val)
+							comp.RenderFunc.emitGo("\nfor " + comp.userCode(comp.src.attrValPos(c, "tx-for", 0), stmt) + " {\n")
+						}
+					}
+				}
+
+				childForKeys := forKeys
+				if hasFor {
+					childForKeys = append(forKeys, forKey)
+				}
+
+				merr.concat(comp.parseTmpl(c, childForKeys, inSlot))
+
+				if hasFor {
+					comp.RenderFunc.emitGo("\n}\n")
+				}
+
+				if c.NextSibling == nil && (prevCondState == CondStateIf || prevCondState == CondStateElseIf || prevCondState == CondStateElse) {
+					comp.RenderFunc.emitGo("\n}\n")
+				}
+			}
+		}
+
+		if node.DataAtom != atom.Template {
+			comp.RenderFunc.emitStrLit("</")
+			comp.RenderFunc.emitStrLit(node.Data)
+			comp.RenderFunc.emitStrLit(">")
+		}
+		return merr
+
+	}
+
+	return nil
+}
+
+// scanTmplStr splits str into raw runes and {expressions}. at maps a byte
+// offset in str to its position in the file, and onExpr gets the offset of
+// the expression.
+func (comp *Component) scanTmplStr(str string, collapseWs bool, at func(i int) token.Pos, onRaw func(r rune), onExpr func(expr string, off int) error) error {
+	if str == "" {
+		return nil
+	}
+
+	braceStack := 0
+	isInDoubleQuote := false
+	isInSingleQuote := false
+	isInBackQuote := false
+	skipNext := false
+	lastWasSpace := false
+
+	expr := []byte{}
+	open := 0
+	for i, r := range str {
+		if skipNext {
+			expr = append(expr, []byte(string(r))...)
+			skipNext = false
+			continue
+		}
+
+		if braceStack == 0 && r != '{' {
+			if collapseWs && (r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f') {
+				if !lastWasSpace {
+					onRaw(' ')
+					lastWasSpace = true
+				}
+				continue
+			}
+			onRaw(r)
+			lastWasSpace = false
+			continue
+		}
+		lastWasSpace = false
+
+		switch r {
+		case '{':
+			if braceStack == 0 {
+				braceStack++
+				open = i
+			} else if isInDoubleQuote || isInSingleQuote || isInBackQuote {
+				expr = append(expr, byte(r))
+			} else {
+				braceStack++
+				expr = append(expr, byte(r))
+			}
+		case '}':
+			if isInDoubleQuote || isInSingleQuote || isInBackQuote {
+				expr = append(expr, byte(r))
+			} else if braceStack == 1 {
+				braceStack--
+				trimmedCurrExpr := bytes.TrimSpace(expr)
+				if len(trimmedCurrExpr) == 0 {
+					continue
+				}
+				off := open + 1 + len(expr) - len(bytes.TrimLeft(expr, " \t\n\r\f\v"))
+				if err := onExpr(string(trimmedCurrExpr), off); err != nil {
+					return err
+				}
+				expr = []byte{}
+			} else {
+				braceStack--
+				expr = append(expr, byte(r))
+			}
+		case '"':
+			if isInSingleQuote || isInBackQuote {
+				expr = append(expr, byte(r))
+			} else {
+				isInDoubleQuote = !isInDoubleQuote
+				expr = append(expr, byte(r))
+			}
+		case '\'':
+			if isInDoubleQuote || isInBackQuote {
+				expr = append(expr, byte(r))
+			} else {
+				isInSingleQuote = !isInSingleQuote
+				expr = append(expr, byte(r))
+			}
+		case '`':
+			if isInDoubleQuote || isInSingleQuote {
+				expr = append(expr, byte(r))
+			} else {
+				isInBackQuote = !isInBackQuote
+				expr = append(expr, byte(r))
+			}
+		case '\\':
+			if isInDoubleQuote || isInSingleQuote {
+				skipNext = true
+				expr = append(expr, byte(r))
+			} else {
+				expr = append(expr, byte(r))
+			}
+		default:
+			expr = append(expr, byte(r))
+		}
+	}
+
+	if isInDoubleQuote || isInBackQuote || isInSingleQuote {
+		return comp.errf(point(at(open)), CodeExprSyntax, "unclosed quote in: %s", str)
+	}
+	if braceStack != 0 {
+		return comp.errf(point(at(open)), CodeExprSyntax, "unclosed { in: %s", str)
+	}
+
+	return nil
+}
+
+func (comp *Component) parseUsedVarsStr(str string) {
+	noPos := func(int) token.Pos { return token.NoPos }
+	comp.scanTmplStr(str, false, noPos, func(rune) {}, func(expr string, _ int) error {
+		if parsed, err := parser.ParseExpr(expr); err == nil {
+			comp.markUsedVars(parsed)
+		}
+		return nil
+	})
+}
+
+func (comp *Component) parseTmplStr(str string, escape, collapseWs bool, at func(i int) token.Pos) error {
+	return comp.scanTmplStr(str, collapseWs, at, func(r rune) {
+		s := string(r)
+		if escape {
+			s = html.EscapeString(s)
+		}
+		comp.RenderFunc.emitStrLit(s)
+	}, func(expr string, off int) error {
+		if _, err := parser.ParseExpr(expr); err != nil {
+			errOff, msg := syntaxError(err)
+			return comp.errf(point(at(off+errOff)), CodeExprSyntax, "invalid expression {%s}: %s", expr, msg)
+		}
+		code := comp.userCode(at(off), expr)
+		if escape {
+			comp.RenderFunc.emitHtmlEscapeExpr(code)
+		} else {
+			comp.RenderFunc.emitExpr(code)
+		}
+		return nil
+	})
+}
+
+type VarType int
+
+const (
+	VarTypeState VarType = iota
+	VarTypeDerived
+	VarTypeProp
+)
+
+type Var struct {
+	Pos        token.Pos
+	Type       VarType
+	GoName     string
+	SavedField string
+
+	TypePos     token.Pos
+	TypeExpr    string
+	InitExprAst ast.Expr
+	InitExpr    string
+	// PathParam is the wildcard of a //tx:path variable.
+	PathParam string
+	// Binding is the //tx:query, //tx:header, //tx:cookie or //tx:url
+	// directive of a state variable the GET request sets.
+	Binding *Comment
+	// Private is set by //tx:private, which encrypts the variable in the
+	// client.
+	Private bool
+}
+
+// parsesPath reports whether the page handler has to parse the path value of
+// v, which it can only assign as is to a string.
+func (v *Var) parsesPath() bool {
+	return v.PathParam != "" && v.TypeExpr != "string"
+}
+
+// routeWildcards returns the names of the wildcards in the route pattern.
+func routeWildcards(pattern string) []string {
+	var names []string
+	for _, m := range wildcardPattern.FindAllStringSubmatch(pattern, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+var wildcardPattern = regexp.MustCompile(`\{([^{}$.]+)(?:\.\.\.)?\}`)
+
+// isNamedType reports whether expr names a type, possibly from another
+// package.
+func isNamedType(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		_, ok := t.X.(*ast.Ident)
+		return ok
+	}
+	return false
+}
+
+type CommentName string
+
+const (
+	CommentPath   CommentName = "path"
+	CommentProp   CommentName = "prop"
+	CommentQuery  CommentName = "query"
+	CommentHeader CommentName = "header"
+	CommentCookie CommentName = "cookie"
+	CommentURL    CommentName = "url"
+	CommentError  CommentName = "error"
+
+	CommentMiddleware CommentName = "middleware"
+	CommentPrivate    CommentName = "private"
+)
+
+type Comment struct {
+	Name  CommentName
+	Value string
+}
+
+// responseName is the name init() and event handlers reach the response by.
+const responseName = "tx"
+
+// urlPush is the //tx:url option that adds a history entry when the variable
+// changes, instead of replacing the current one.
+const urlPush = "push"
+
+type Func struct {
+	Name  string
+	Decl  *ast.FuncDecl
+	Stmts string
+}
+
+type CondState int
+
+const (
+	CondStateDefault CondState = iota
+	CondStateIf
+	CondStateElseIf
+	CondStateElse
+)
+
+type Fill struct {
+	GoName     string
+	CompName   string
+	ParentComp *Component
+	Location   string
+
+	HasChildComps bool
+	UsedVars      map[string]struct{}
+	RenderFunc    Code
+}
+
+func newTemplateNode() *html.Node {
+	return &html.Node{
+		Type:     html.ElementNode,
+		DataAtom: atom.Template,
+		Data:     "template",
+	}
+}
+
+func goIdent(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) * 3)
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsLetter(r) || r == '_' || (i > 0 && unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case r == '{' && i+2 < len(runes) && runes[i+1] == '$' && runes[i+2] == '}':
+			b.WriteString("_EX_")
+			i += 2
+		case r == '/':
+			b.WriteString("_S_")
+		case r == '-':
+			b.WriteString("_H_")
+		case r == '.':
+			b.WriteString("_O_")
+		case r == ':':
+			b.WriteString("_C_")
+		case r == '@':
+			b.WriteString("_A_")
+		case r == '!':
+			b.WriteString("_B_")
+		case r == '~':
+			b.WriteString("_T_")
+		case r == '*':
+			b.WriteString("_K_")
+		case r == '+':
+			b.WriteString("_P_")
+		case r == '=':
+			b.WriteString("_E_")
+		case r == '&':
+			b.WriteString("_N_")
+		case r == '?':
+			b.WriteString("_Q_")
+		case r == '#':
+			b.WriteString("_F_")
+		case r == '$':
+			b.WriteString("_D_")
+		case r == '{':
+			b.WriteString("_L_")
+		case r == '}':
+			b.WriteString("_R_")
+		default:
+			fmt.Fprintf(&b, "_%X_", r)
+		}
+	}
+	return b.String()
+}
+
+func isTmplxScriptNode(node *html.Node) bool {
+	if node.DataAtom != atom.Script {
+		return false
+	}
+
+	for _, attr := range node.Attr {
+		if attr.Key == "type" && attr.Val == "text/tmplx" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasAttr(n *html.Node, str string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == str {
+			return attr.Val, true
+		}
+	}
+
+	return "", false
+}
+
+func cleanUpTmplxScript(node *html.Node) {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if isTmplxScriptNode(c) {
+			n := c.NextSibling
+			node.RemoveChild(c)
+			c.NextSibling = n
+			continue
+		}
+		cleanUpTmplxScript(c)
+	}
+}
+
+func astToSource(a ast.Node) string {
+	var buf strings.Builder
+	printer.Fprint(&buf, token.NewFileSet(), a)
+	return buf.String()
+}
+
+func parseComments(text string) []Comment {
+	comments := []Comment{}
+
+	if text[1] == '*' {
+		text = text[2 : len(text)-2]
+	} else {
+		text = text[2:]
+	}
+
+	lines := strings.SplitSeq(text, "\n")
+	for line := range lines {
+		str := strings.TrimSpace(line)
+		if str == "tx:prop" {
+			comments = append(comments, Comment{
+				Name: CommentProp,
+			})
+		} else if str == "tx:error" {
+			comments = append(comments, Comment{
+				Name: CommentError,
+			})
+		} else if str == "tx:private" {
+			comments = append(comments, Comment{
+				Name: CommentPrivate,
+			})
+		} else if strings.HasPrefix(str, "tx:path") {
+			val := strings.TrimSpace(str[len("tx:path"):])
+			comments = append(comments, Comment{
+				Name:  CommentPath,
+				Value: val,
+			})
+		} else {
+			for _, name := range []CommentName{CommentQuery, CommentHeader, CommentCookie, CommentURL, CommentMiddleware} {
+				if val, ok := strings.CutPrefix(str, "tx:"+string(name)); ok && (val == "" || val[0] == ' ' || val[0] == '\t') {
+					comments = append(comments, Comment{
+						Name:  name,
+						Value: strings.TrimSpace(val),
+					})
+				}
+			}
+		}
+	}
+
+	return comments
+}
+
+func condState(n *html.Node) (CondState, string) {
+	for _, attr := range n.Attr {
+		if attr.Key == "tx-if" {
+			return CondStateIf, attr.Val
+		}
+
+		if attr.Key == "tx-else-if" {
+			return CondStateElseIf, attr.Val
+		}
+
+		if attr.Key == "tx-else" {
+			return CondStateElse, ""
+		}
+	}
+	return CondStateDefault, ""
+}
+
+func (comp *Component) parseFillNodes(n *html.Node) map[string]*html.Node {
+	fillNodes := map[string]*html.Node{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if slotName, found := hasAttr(c, "slot"); found {
+			fillNodes[slotName] = c
+			continue
+		} else {
+			if fillNodes[""] == nil {
+				fillNodes[""] = newTemplateNode()
+			}
+
+			fillNodes[""].AppendChild(comp.src.copyNode(c))
+		}
+	}
+	return fillNodes
+}
+
+// https://html.spec.whatwg.org/#void-elements
+func isVoidElement(name string) bool {
+	switch name {
+	case "area":
+		return true
+	case "base":
+		return true
+	case "br":
+		return true
+	case "col":
+		return true
+	case "embed":
+		return true
+	case "hr":
+		return true
+	case "img":
+		return true
+	case "input":
+		return true
+	case "link":
+		return true
+	case "meta":
+		return true
+	case "source":
+		return true
+	case "track":
+		return true
+	case "wbr":
+		return true
+	}
+	return false
+}
+
+// https://html.spec.whatwg.org/#parsing-html-fragments
+func isChildNodeRawText(name string) bool {
+	switch name {
+	case "title":
+		return true
+	case "textarea":
+		return true
+	case "style":
+		return true
+	case "xmp":
+		return true
+	case "iframe":
+		return true
+	case "noembed":
+		return true
+	case "noframes":
+		return true
+	case "script":
+		return true
+	case "noscript":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,191 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Diagnostic is a problem found in a page or component. File is the path of
+// the source file inside the compiled fs.FS, or empty when the problem is not
+// tied to a single file.
+type Diagnostic struct {
+	File     string         `json:"file,omitempty"`
+	Range    Range          `json:"range,omitzero"`
+	Severity Severity       `json:"severity"`
+	Code     DiagnosticCode `json:"code"`
+	Message  string         `json:"message"`
+}
+
+func (d Diagnostic) Error() string {
+	msg := d.Message
+	if d.Severity == SeverityWarning {
+		msg = "warning: " + msg
+	}
+	if d.File == "" {
+		return msg
+	}
+	if d.Range.Start.Line == 0 {
+		return d.File + ": " + msg
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Range.Start.Line, d.Range.Start.Column, msg)
+}
+
+// Range is the part of File a Diagnostic is about, from Start up to but not
+// including End. It is zero when the problem has no position in the file.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Position is a 1-based line and column, with the column counted in bytes.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// DiagnosticCode identifies the kind of a Diagnostic. Codes are stable across
+// releases so that tools can match on them instead of on messages.
+type DiagnosticCode string
+
+const (
+	CodeFileAccess         DiagnosticCode = "file-access"
+	CodeFileName           DiagnosticCode = "file-name"
+	CodeDuplicateComponent DiagnosticCode = "duplicate-component"
+	CodeDuplicateRoute     DiagnosticCode = "duplicate-route"
+	CodeDuplicateLayout    DiagnosticCode = "duplicate-layout"
+
+	CodeInvalidHTML      DiagnosticCode = "invalid-html"
+	CodeDuplicateElement DiagnosticCode = "duplicate-element"
+	CodeMissingHead      DiagnosticCode = "missing-head"
+	CodeNestedSlot       DiagnosticCode = "nested-slot"
+	CodeDuplicateSlot    DiagnosticCode = "duplicate-slot"
+	CodeLayoutSlot       DiagnosticCode = "layout-slot"
+
+	CodeScriptSyntax     DiagnosticCode = "script-syntax"
+	CodeInvalidDecl      DiagnosticCode = "invalid-decl"
+	CodeUnknownImport    DiagnosticCode = "unknown-import"
+	CodeUnusedImport     DiagnosticCode = "unused-import"
+	CodeImportCollision  DiagnosticCode = "import-collision"
+	CodeMultipleVars     DiagnosticCode = "multiple-vars"
+	CodeMissingType      DiagnosticCode = "missing-type"
+	CodeReservedName     DiagnosticCode = "reserved-name"
+	CodeInvalidDirective DiagnosticCode = "invalid-directive"
+	CodeUseBeforeDecl    DiagnosticCode = "use-before-decl"
+	CodeInvalidFunc      DiagnosticCode = "invalid-func"
+	CodeShadow           DiagnosticCode = "shadow"
+	CodeReadOnlyAssign   DiagnosticCode = "read-only-assign"
+	CodeUnusedVar        DiagnosticCode = "unused-var"
+
+	CodeUnknownProp     DiagnosticCode = "unknown-prop"
+	CodeNotProp         DiagnosticCode = "not-a-prop"
+	CodePropType        DiagnosticCode = "prop-type"
+	CodeUndefinedFunc   DiagnosticCode = "undefined-func"
+	CodeMissingFuncProp DiagnosticCode = "missing-func-prop"
+	CodeHandlerArgs     DiagnosticCode = "handler-args"
+	CodeInvalidHandler  DiagnosticCode = "invalid-handler"
+	CodeActionElement   DiagnosticCode = "action-element"
+	CodeVoidChildren    DiagnosticCode = "void-children"
+	CodeElseWithoutIf   DiagnosticCode = "else-without-if"
+	CodeElseAfterElse   DiagnosticCode = "else-after-else"
+	CodeForWithoutKey   DiagnosticCode = "for-without-key"
+	CodeExprSyntax      DiagnosticCode = "expr-syntax"
+
+	CodeURLFuncName  DiagnosticCode = "url-func-name"
+	CodeUnknownRoute DiagnosticCode = "unknown-route"
+
+	CodeRouteMiddleware DiagnosticCode = "route-middleware"
+
+	CodeGenerate  DiagnosticCode = "generate"
+	CodeTypeError DiagnosticCode = "type-error"
+)
+
+// ErrorList is the error returned when compilation fails. It holds the same
+// diagnostics as Result.Diagnostics.
+type ErrorList []Diagnostic
+
+func (list ErrorList) Error() string {
+	msgs := make([]string, len(list))
+	for i, d := range list {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+type multiError struct {
+	errs []error
+	mux  sync.Mutex
+}
+
+func newMultiError(errs ...error) *multiError {
+	me := &multiError{}
+	for _, err := range errs {
+		if err != nil {
+			me.errs = append(me.errs, err)
+		}
+	}
+	return me
+}
+
+func (me *multiError) append(errs ...error) {
+	me.mux.Lock()
+	defer me.mux.Unlock()
+	for _, err := range errs {
+		if err != nil {
+			me.errs = append(me.errs, err)
+		}
+	}
+}
+
+func (me *multiError) concat(other *multiError) {
+	if other == nil {
+		return
+	}
+	other.mux.Lock()
+	errs := make([]error, len(other.errs))
+	copy(errs, other.errs)
+	other.mux.Unlock()
+
+	me.append(errs...)
+}
+
+func (me *multiError) empty() bool {
+	me.mux.Lock()
+	defer me.mux.Unlock()
+	return len(me.errs) == 0
+}
+
+func (me *multiError) diagnostics() []Diagnostic {
+	me.mux.Lock()
+	defer me.mux.Unlock()
+	diags := make([]Diagnostic, 0, len(me.errs))
+	for _, err := range me.errs {
+		var d Diagnostic
+		if !errors.As(err, &d) {
+			d = Diagnostic{Message: err.Error()}
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
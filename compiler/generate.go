@@ -0,0 +1,1974 @@
+package compiler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/imports"
+)
+
+// generatedHeader starts every generated file. It follows the convention
+// for generated Go files, so tools skip them and IsGenerated can tell them
+// apart from files written by hand.
+const generatedHeader = "// Code generated by tmplx. DO NOT EDIT.\n"
+
+// IsGenerated reports whether src is a file written by the compiler.
+func IsGenerated(src []byte) bool {
+	return bytes.HasPrefix(src, []byte(generatedHeader))
+}
+
+// routesFileName is the name of the file that lists the routes of every
+// page and component when Config.OutputDir is set.
+const routesFileName = "routes.go"
+
+// outputFile is a generated Go file.
+type outputFile struct {
+	// name is the file name in the output directory.
+	name   string
+	source []byte
+	// decls are the declarations of source before formatting, still
+	// holding the marks added by userCode.
+	decls string
+}
+
+// generate returns the output file, or with OutputDir one file per page and
+// component plus routes.go.
+func (c *Compiler) generate() ([]outputFile, error) {
+	if c.cfg.OutputDir == "" {
+		var code CodeBuilder
+		c.writePackage(&code, c.sources())
+		declsStart := code.Len()
+		c.writeRuntime(&code)
+		for _, comp := range c.components {
+			c.writeCompDecls(&code, comp)
+		}
+		for _, layout := range c.layouts {
+			c.writeLayoutDecls(&code, layout)
+		}
+		for _, page := range c.pages {
+			c.writePageDecls(&code, page)
+		}
+		c.writeRouteType(&code)
+		code.write("var txRoutes []TxRoute = []TxRoute{\n")
+		for _, page := range c.pages {
+			c.writePageRoutes(&code, page)
+		}
+		for _, comp := range c.components {
+			c.writeCompRoutes(&code, comp)
+		}
+		code.write("}\n")
+
+		file, err := c.format(filepath.Base(c.cfg.OutputFile), &code, declsStart)
+		if err != nil {
+			return nil, err
+		}
+		return []outputFile{file}, nil
+	}
+
+	files := []outputFile{}
+	taken := map[string]struct{}{routesFileName: {}}
+	routeVars := []string{}
+	for _, comp := range slices.Concat(c.pages, c.layouts, c.components) {
+		var code CodeBuilder
+		c.writePackage(&code, c.importers(comp))
+		declsStart := code.Len()
+		routeVar := "routes_" + comp.GoName
+		switch comp.Type {
+		case CompTypePage:
+			c.writePageDecls(&code, comp)
+			code.write("var %s = []TxRoute{\n", routeVar)
+			c.writePageRoutes(&code, comp)
+			code.write("}\n")
+			routeVars = append(routeVars, routeVar)
+		case CompTypeLayout:
+			// The routes of layout events are listed with the pages.
+			c.writeLayoutDecls(&code, comp)
+		default:
+			c.writeCompDecls(&code, comp)
+			code.write("var %s = []TxRoute{\n", routeVar)
+			c.writeCompRoutes(&code, comp)
+			code.write("}\n")
+			routeVars = append(routeVars, routeVar)
+		}
+
+		file, err := c.format(outputFileName(comp, taken), &code, declsStart)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	var code CodeBuilder
+	c.writePackage(&code, nil)
+	declsStart := code.Len()
+	c.writeRuntime(&code)
+	c.writeRouteType(&code)
+	if len(routeVars) == 0 {
+		code.write("var txRoutes []TxRoute\n")
+	} else {
+		code.write("var txRoutes = slices.Concat(\n%s,\n)\n", strings.Join(routeVars, ",\n"))
+	}
+	file, err := c.format(routesFileName, &code, declsStart)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, file), nil
+}
+
+// importers returns comp together with the pages and components whose Go
+// code the file generated for comp copies: the components it renders, for a
+// page the layouts whose middleware wrap its routes, and for a component the
+// pages and components that fill its slots and those whose middleware wrap
+// its routes.
+func (c *Compiler) importers(comp *Component) []*Component {
+	comps := []*Component{comp}
+	for name := range comp.ChildComps {
+		if child, ok := c.componentsByName[name]; ok && !slices.Contains(comps, child) {
+			comps = append(comps, child)
+		}
+	}
+	for _, fill := range comp.CompFills {
+		if !slices.Contains(comps, fill.ParentComp) {
+			comps = append(comps, fill.ParentComp)
+		}
+	}
+	if comp.Type == CompTypePage {
+		comps = append(comps, comp.layouts()...)
+	}
+	if page := comp.routeMiddlewarePage; page != nil {
+		for _, p := range append([]*Component{page}, page.layouts()...) {
+			if !slices.Contains(comps, p) {
+				comps = append(comps, p)
+			}
+		}
+	}
+	slices.SortStableFunc(comps[1:], func(a, b *Component) int {
+		return strings.Compare(a.FilePath, b.FilePath)
+	})
+	return comps
+}
+
+// outputFileName returns the name of the file generated for comp, such as
+// page.blog.index.tx.go for pages/blog/index.html, and records it in taken.
+// The go command would read a suffix such as _linux before the first dot as
+// a build constraint and one such as _test.go as a test file, so the name
+// starts with the kind of comp and ends in .tx.go.
+func outputFileName(comp *Component, taken map[string]struct{}) string {
+	kind := "comp"
+	switch comp.Type {
+	case CompTypePage:
+		kind = "page"
+	case CompTypeLayout:
+		kind = "layout"
+	}
+	stem, _ := strings.CutSuffix(comp.RelPath, ".html")
+	stem = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/':
+			return '.'
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, stem)
+
+	// File systems may ignore case, so names must differ in more than that.
+	name := kind + "." + stem + ".tx.go"
+	for i := 2; ; i++ {
+		if _, ok := taken[strings.ToLower(name)]; !ok {
+			break
+		}
+		name = fmt.Sprintf("%s.%s.%d.tx.go", kind, stem, i)
+	}
+	taken[strings.ToLower(name)] = struct{}{}
+	return name
+}
+
+// writePackage writes the header, package clause and the imports of comps.
+// Imports a file does not use are removed when it is formatted.
+func (c *Compiler) writePackage(code *CodeBuilder, comps []*Component) {
+	code.write(generatedHeader)
+	code.write("\n")
+	code.write("package %s\n", c.cfg.PackageName)
+
+	code.write("import(\n")
+	for _, comp := range comps {
+		for _, im := range comp.Imports {
+			code.write("%s\n", astToSource(im))
+		}
+	}
+	code.write(")\n")
+}
+
+func (c *Compiler) writeRuntime(code *CodeBuilder) {
+	code.write("var runtimeScript = `%s`\n", runtimeScript)
+	code.write("// txHandlerPrefix is the path prefix of the event handler routes.\n")
+	code.write("const txHandlerPrefix = %q\n", c.cfg.HandlerPrefix)
+	code.write("// txCompactState compresses large state, with -state-encoding=compact.\n")
+	code.write("const txCompactState = %t\n", c.cfg.StateEncoding == StateEncodingCompact)
+	code.write("%s", requestValueRuntime)
+
+	notFound := "http.NotFound(w, r)"
+	internalError := "http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)"
+	for _, page := range c.pages {
+		switch page.ErrorStatus {
+		case http.StatusNotFound:
+			notFound = fmt.Sprintf("serve_%s(w, r, err)", page.GoName)
+		case http.StatusInternalServerError:
+			internalError = fmt.Sprintf("serve_%s(w, r, err)", page.GoName)
+		}
+	}
+	code.write("// TxNotFound answers requests for pages that do not exist and for pages whose\n")
+	code.write("// path values cannot be parsed into their //tx:path variables. It serves the\n")
+	code.write("// %s page if there is one. Replace it to handle them yourself.\n", notFoundFileName)
+	code.write("var TxNotFound = func(w http.ResponseWriter, r *http.Request, err error) {\n%s\n}\n", notFound)
+	code.write("// TxError answers requests whose handler panicked, with the panic value as\n")
+	code.write("// err. It serves the %s page if there is one. Replace it to handle\n", errorFileName)
+	code.write("// them yourself.\n")
+	code.write("var TxError = func(w http.ResponseWriter, r *http.Request, err error) {\n%s\n}\n", internalError)
+	code.write("%s", recoverRuntime)
+	code.write("%s", responseRuntime)
+	code.write("%s", stateRuntime)
+	code.write("%s", storeRuntime)
+	code.write("%s", handlerRuntime)
+}
+
+// handlerRuntime is NewHandler and its options. The handler passes its
+// settings to the routes in the request context, and the routes fall back to
+// txDefaultConfig when they are registered from Routes.
+const handlerRuntime = `
+type txConfig struct {
+	prefix        string
+	middleware    []func(http.Handler) http.Handler
+	onNotFound    func(http.ResponseWriter, *http.Request, error)
+	onError       func(http.ResponseWriter, *http.Request, error)
+	runtimeScript string
+	// signingKeys and encryptionKeys are derived from the keys of
+	// WithStateKeys. The first signs or encrypts state, and all of them
+	// verify or decrypt it.
+	signingKeys    [][]byte
+	encryptionKeys [][]byte
+	store          StateStore
+	migrate        func(component, version string, state map[string]any) error
+}
+
+var txDefaultConfig = &txConfig{
+	runtimeScript:  strings.Replace(runtimeScript, "TX_HANDLER_PREFIX", txHandlerPrefix, 1),
+	encryptionKeys: [][]byte{txRandomKey()},
+}
+
+func (cfg *txConfig) notFound(w http.ResponseWriter, r *http.Request, err error) {
+	if cfg.onNotFound != nil {
+		cfg.onNotFound(w, r, err)
+		return
+	}
+	TxNotFound(w, r, err)
+}
+
+func (cfg *txConfig) error(w http.ResponseWriter, r *http.Request, err error) {
+	if cfg.onError != nil {
+		cfg.onError(w, r, err)
+		return
+	}
+	TxError(w, r, err)
+}
+
+type txConfigKey struct{}
+
+func txConfigOf(r *http.Request) *txConfig {
+	if cfg, ok := r.Context().Value(txConfigKey{}).(*txConfig); ok {
+		return cfg
+	}
+	return txDefaultConfig
+}
+
+// Option configures the handler NewHandler returns.
+type Option func(*txConfig)
+
+// WithPrefix mounts the pages and event handlers under prefix, such as
+// "/admin", which is stripped from request paths before routing.
+func WithPrefix(prefix string) Option {
+	return func(cfg *txConfig) {
+		cfg.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// WithMiddleware wraps the handler in middleware, the first one outermost.
+func WithMiddleware(middleware ...func(http.Handler) http.Handler) Option {
+	return func(cfg *txConfig) {
+		cfg.middleware = append(cfg.middleware, middleware...)
+	}
+}
+
+// WithNotFound sets the function that answers unknown URLs and path values
+// that cannot be parsed, in place of TxNotFound.
+func WithNotFound(notFound func(http.ResponseWriter, *http.Request, error)) Option {
+	return func(cfg *txConfig) {
+		cfg.onNotFound = notFound
+	}
+}
+
+// WithErrorHandler sets the function that answers requests whose handler
+// panicked, in place of TxError.
+func WithErrorHandler(handler func(http.ResponseWriter, *http.Request, error)) Option {
+	return func(cfg *txConfig) {
+		cfg.onError = handler
+	}
+}
+
+// WithStateKeys signs the state pages and components keep in the client with
+// the first key, and makes the handler reject state that none of the keys
+// signed with status 400. //tx:private variables are encrypted with the first
+// key too. Without keys they are encrypted with a key made when the program
+// starts, which other instances and restarts cannot decrypt. To rotate keys,
+// put the new key first and keep the old one after it until the pages
+// rendered with it are gone. WithStateKeys panics if a key is shorter than
+// 32 bytes.
+func WithStateKeys(keys ...[]byte) Option {
+	for _, key := range keys {
+		if len(key) < txMinKeySize {
+			panic(fmt.Sprintf("WithStateKeys: key of %d bytes, want at least %d", len(key), txMinKeySize))
+		}
+	}
+	return func(cfg *txConfig) {
+		cfg.signingKeys, cfg.encryptionKeys = nil, nil
+		for _, key := range keys {
+			cfg.signingKeys = append(cfg.signingKeys, txDeriveKey(key, "tx-state signing"))
+			cfg.encryptionKeys = append(cfg.encryptionKeys, txDeriveKey(key, "tx-state encryption"))
+		}
+		if len(keys) == 0 {
+			cfg.encryptionKeys = txDefaultConfig.encryptionKeys
+		}
+	}
+}
+
+// txMinKeySize is the smallest size of a key of WithStateKeys.
+const txMinKeySize = 32
+
+// WithStateMigration sets the function that converts the state of a page,
+// layout or component rendered before a deploy that changed its variables.
+// It gets the component name, the version the state was saved with and the
+// state by JSON name, which it changes to match the variables of the current
+// build. Without a migration function, or if it returns an error, the runtime
+// reloads the page instead. With the compact state encoding, the JSON names
+// are the positions of the variables in the build that saved the state, so
+// migration needs the json encoding.
+func WithStateMigration(migrate func(component, version string, state map[string]any) error) Option {
+	return func(cfg *txConfig) {
+		cfg.migrate = migrate
+	}
+}
+
+// NewHandler returns a handler that serves every page and event handler on
+// its own mux.
+func NewHandler(opts ...Option) http.Handler {
+	cfg := txNewConfig(opts)
+	mux := http.NewServeMux()
+	for _, route := range txRoutes {
+		mux.HandleFunc(route.Pattern, route.Handler)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		cfg.notFound(w, r, errors.New(http.StatusText(http.StatusNotFound)))
+	})
+
+	var h http.Handler = txChain(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), txConfigKey{}, cfg)))
+	}, cfg.middleware...)
+	if cfg.prefix != "" {
+		h = http.StripPrefix(cfg.prefix, h)
+	}
+	return h
+}
+
+// Routes returns every page and event handler route, for registering them
+// on a mux of your own. The options apply to each route as they do to
+// NewHandler, except that routes are registered without WithPrefix, which
+// only sets where the runtime sends events.
+func Routes(opts ...Option) []TxRoute {
+	if len(opts) == 0 {
+		return txRoutes
+	}
+	cfg := txNewConfig(opts)
+	routes := make([]TxRoute, len(txRoutes))
+	for i, route := range txRoutes {
+		handler := route.Handler
+		routes[i] = TxRoute{Pattern: route.Pattern, Handler: txChain(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r.WithContext(context.WithValue(r.Context(), txConfigKey{}, cfg)))
+		}, cfg.middleware...)}
+	}
+	return routes
+}
+
+// txNewConfig returns txDefaultConfig with opts applied.
+func txNewConfig(opts []Option) *txConfig {
+	cfg := *txDefaultConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.runtimeScript = strings.Replace(runtimeScript, "TX_HANDLER_PREFIX", cfg.prefix+txHandlerPrefix, 1)
+	return &cfg
+}
+
+// txChain wraps h with middleware, the first outermost.
+func txChain(h http.HandlerFunc, middleware ...func(http.Handler) http.Handler) http.HandlerFunc {
+	var wrapped http.Handler = h
+	for i := len(middleware) - 1; i >= 0; i-- {
+		wrapped = middleware[i](wrapped)
+	}
+	return wrapped.ServeHTTP
+}
+`
+
+// responseRuntime is the tx value init() and event handlers use to change the
+// response.
+const responseRuntime = `
+// TxResponse is the response of the request a page or component handles. The
+// init() and event handler functions of tmplx scripts reach it as tx.
+type TxResponse struct {
+	w          http.ResponseWriter
+	r          *http.Request
+	cfg        *txConfig
+	status     int
+	redirected bool
+	// reload is set when state the client sent cannot be restored, to
+	// answer with a reload of the page, and invalid as well when the state
+	// was altered, to answer with 400 instead.
+	reload  bool
+	invalid bool
+	// session is the StateStore session of the state, and sent the ids of
+	// the state the client sent with the event.
+	session string
+	sent    []string
+}
+
+func newTxResponse(w http.ResponseWriter, r *http.Request) *TxResponse {
+	return &TxResponse{w: w, r: r, cfg: txConfigOf(r)}
+}
+
+// Redirect answers the request with a redirect to url instead of the page.
+// After an event, the runtime navigates to url.
+func (tx *TxResponse) Redirect(url string) {
+	if tx.redirected {
+		return
+	}
+	tx.redirected = true
+	if tx.r.Method == http.MethodPost {
+		tx.w.Header().Set("Tx-Redirect", url)
+		tx.w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Redirect(tx.w, tx.r, url, http.StatusFound)
+}
+
+// Status sets the status code the page is sent with.
+func (tx *TxResponse) Status(code int) {
+	tx.status = code
+}
+
+// SetHeader sets a header of the response.
+func (tx *TxResponse) SetHeader(key, value string) {
+	tx.w.Header().Set(key, value)
+}
+
+// SetCookie adds a Set-Cookie header to the response.
+func (tx *TxResponse) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(tx.w, cookie)
+}
+
+func (tx *TxResponse) write(parts ...[]byte) {
+	if tx.redirected {
+		return
+	}
+	if tx.invalid {
+		http.Error(tx.w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if tx.reload {
+		tx.w.Header().Set("Tx-Reload", "true")
+		tx.w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if tx.status != 0 {
+		tx.w.WriteHeader(tx.status)
+	}
+	for _, part := range parts {
+		tx.w.Write(part)
+	}
+}
+`
+
+// stateRuntime encodes the state of the pages and components a response
+// renders for the tx-saved script, and decodes the state the runtime sends
+// back in the tx-state field of event requests.
+const stateRuntime = `
+// readState returns the state the client sent with an event by id, as the
+// tx-saved entries unmarshalState decodes, or as JSON with a StateStore.
+func (tx *TxResponse) readState() (map[string]string, error) {
+	saved := map[string]json.RawMessage{}
+	if form := tx.r.PostFormValue("tx-state"); form != "" {
+		if err := json.Unmarshal([]byte(form), &saved); err != nil {
+			return nil, err
+		}
+	}
+	state := make(map[string]string, len(saved))
+	for id, entry := range saved {
+		tx.sent = append(tx.sent, id)
+		if tx.cfg.store != nil {
+			data, err := tx.loadState(id, entry)
+			if err != nil {
+				return nil, fmt.Errorf("state %s: %w", id, err)
+			}
+			if data == nil {
+				// The session expired, and the page starts over.
+				tx.reload = true
+				continue
+			}
+			state[id] = string(data)
+			continue
+		}
+		state[id] = string(entry)
+	}
+	return state, nil
+}
+
+// encodeState returns the content of the tx-saved script for state.
+func (tx *TxResponse) encodeState(state map[string]any) ([]byte, error) {
+	if tx.reload {
+		return nil, nil
+	}
+	saved := make(map[string]json.RawMessage, len(state))
+	for id, v := range state {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("state %s: %w", id, err)
+		}
+		s := v.(txState)
+		data = txAddVersion(data, s.txVersion())
+		if tx.cfg.store != nil {
+			if saved[id], err = tx.storeState(id, data); err != nil {
+				return nil, fmt.Errorf("state %s: %w", id, err)
+			}
+			continue
+		}
+		if p, ok := v.(interface{ txPrivate() []string }); ok {
+			if data, err = tx.cfg.seal(s.txName(), id, data, p.txPrivate()); err != nil {
+				return nil, fmt.Errorf("state %s: %w", id, err)
+			}
+		}
+		if saved[id], err = tx.cfg.encodeState(s.txName(), id, data); err != nil {
+			return nil, fmt.Errorf("state %s: %w", id, err)
+		}
+	}
+	if tx.cfg.store != nil {
+		if err := tx.deleteState(state); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(saved)
+}
+
+// txVersions caches txTypeVersion by type.
+var txVersions sync.Map
+
+// txTypeVersion returns a fingerprint of the JSON shape of the state struct
+// s points to: the JSON names and types of its fields, down to the fields of
+// the structs they hold, and its private fields. Types with their own JSON
+// or text encoding, such as time.Time, count by name. The Go names of its
+// own fields count too, as they hold the variable names, which the JSON
+// names of compact state do not.
+func txTypeVersion(s txState) string {
+	t := reflect.TypeOf(s)
+	if version, ok := txVersions.Load(t); ok {
+		return version.(string)
+	}
+	h := sha256.New()
+	for i := range t.Elem().NumField() {
+		fmt.Fprint(h, t.Elem().Field(i).Name, " ")
+	}
+	txWriteType(h, t.Elem(), map[reflect.Type]bool{})
+	if p, ok := s.(interface{ txPrivate() []string }); ok {
+		fmt.Fprint(h, " private ", p.txPrivate())
+	}
+	version := hex.EncodeToString(h.Sum(nil))[:8]
+	txVersions.Store(t, version)
+	return version
+}
+
+var (
+	txJSONMarshaler = reflect.TypeFor[json.Marshaler]()
+	txTextMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// txWriteType writes the JSON shape of t to w for txTypeVersion. seen holds
+// the structs being written, which a recursive type refers to by name.
+func txWriteType(w io.Writer, t reflect.Type, seen map[reflect.Type]bool) {
+	if t.Implements(txJSONMarshaler) || t.Implements(txTextMarshaler) || reflect.PointerTo(t).Implements(txJSONMarshaler) || reflect.PointerTo(t).Implements(txTextMarshaler) {
+		fmt.Fprint(w, t.String())
+		return
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice:
+		fmt.Fprint(w, t.Kind(), " ")
+		txWriteType(w, t.Elem(), seen)
+	case reflect.Array:
+		fmt.Fprint(w, "array ", t.Len(), " ")
+		txWriteType(w, t.Elem(), seen)
+	case reflect.Map:
+		fmt.Fprint(w, "map ")
+		txWriteType(w, t.Key(), seen)
+		fmt.Fprint(w, " ")
+		txWriteType(w, t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			fmt.Fprint(w, t.String())
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		fmt.Fprint(w, "struct {")
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			fmt.Fprintf(w, "%q ", name)
+			txWriteType(w, f.Type, seen)
+			fmt.Fprint(w, "; ")
+		}
+		fmt.Fprint(w, "}")
+	default:
+		fmt.Fprint(w, t.Kind())
+	}
+}
+
+// txAddVersion adds the tx-v field with version to the JSON object data.
+func txAddVersion(data []byte, version string) []byte {
+	field := []byte("\"tx-v\":\"" + version + "\"")
+	if string(data) == "{}" {
+		return slices.Concat([]byte("{"), field, []byte("}"))
+	}
+	return slices.Concat([]byte("{"), field, []byte(","), data[1:])
+}
+
+// txState is implemented by the state structs of pages, layouts and
+// components. txName returns the page path or component name.
+type txState interface {
+	txName() string
+	txVersion() string
+}
+
+// checkState sets tx to answer an event whose state has no entry for one of
+// ids, the page, layouts or component the event runs on. Entries missing from
+// signed state were dropped by the client, and are answered with 400.
+// Otherwise, or when a StateStore session already expired, the page reloads.
+func (tx *TxResponse) checkState(saved map[string]string, ids ...string) {
+	for _, id := range ids {
+		if _, ok := saved[id]; !ok {
+			if !tx.reload {
+				tx.invalid = len(tx.cfg.signingKeys) > 0
+			}
+			tx.reload = true
+			return
+		}
+	}
+}
+
+// unmarshalState decodes the state data of id into dst, checking its
+// signature and decrypting its private fields. If its tx-v field is not the version of dst, the state was
+// saved before a deploy that changed the component's variables. It is then
+// converted with the WithStateMigration function, and if there is none or it
+// fails, tx is set to reload the page.
+func (tx *TxResponse) unmarshalState(id, data string, dst txState) {
+	if data == "" {
+		return
+	}
+	if tx.cfg.store == nil {
+		decoded, err := tx.cfg.decodeState(dst.txName(), id, json.RawMessage(data))
+		if err != nil {
+			tx.reload, tx.invalid = true, true
+			return
+		}
+		data = string(decoded)
+	}
+	if p, ok := dst.(interface{ txPrivate() []string }); ok && tx.cfg.store == nil {
+		opened, err := tx.cfg.open(dst.txName(), id, []byte(data), p.txPrivate())
+		if err != nil {
+			// State encrypted with a key the handler no longer has, such
+			// as the default key of an earlier run, starts over.
+			tx.reload = true
+			tx.invalid = tx.invalid || !errors.Is(err, txErrDecrypt)
+			return
+		}
+		data = string(opened)
+	}
+	state := map[string]any{}
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		tx.reload = true
+		return
+	}
+	version, _ := state["tx-v"].(string)
+	if version == dst.txVersion() {
+		json.Unmarshal([]byte(data), dst)
+		return
+	}
+	if tx.cfg.migrate == nil {
+		tx.reload = true
+		return
+	}
+	delete(state, "tx-v")
+	if err := tx.cfg.migrate(dst.txName(), version, state); err != nil {
+		tx.reload = true
+		return
+	}
+	migrated, err := json.Marshal(state)
+	if err == nil {
+		err = json.Unmarshal(migrated, dst)
+	}
+	if err != nil {
+		tx.reload = true
+	}
+}
+
+// encodeState returns the tx-saved entry of the state data of id, which the
+// page or component name renders. Signed entries are strings of the data and
+// its signature, separated by a dot. Compressed data is a string too.
+func (cfg *txConfig) encodeState(name, id string, data []byte) (json.RawMessage, error) {
+	if txCompactState && len(data) > txCompressMin {
+		data = txCompress(data)
+	}
+	if len(cfg.signingKeys) == 0 {
+		if data[0] == txCompressed {
+			return json.Marshal(string(data))
+		}
+		return data, nil
+	}
+	return json.Marshal(string(data) + "." + txSign(cfg.signingKeys[0], name, id, data))
+}
+
+// decodeState returns the state data of the tx-saved entry of id, which the
+// page or component name renders, after checking its signature.
+func (cfg *txConfig) decodeState(name, id string, entry json.RawMessage) ([]byte, error) {
+	if len(cfg.signingKeys) == 0 {
+		var compressed string
+		if json.Unmarshal(entry, &compressed) == nil {
+			return txDecompress([]byte(compressed))
+		}
+		return entry, nil
+	}
+	var signed string
+	if err := json.Unmarshal(entry, &signed); err != nil {
+		return nil, errors.New("state is not signed")
+	}
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return nil, errors.New("state is not signed")
+	}
+	data, sig := []byte(signed[:i]), signed[i+1:]
+	for _, key := range cfg.signingKeys {
+		if hmac.Equal([]byte(sig), []byte(txSign(key, name, id, data))) {
+			if len(data) > 0 && data[0] == txCompressed {
+				return txDecompress(data)
+			}
+			return data, nil
+		}
+	}
+	return nil, errors.New("invalid state signature")
+}
+
+const (
+	// txCompressMin is the size from which compact state is compressed.
+	txCompressMin = 512
+	// txCompressed starts compressed state, which JSON cannot start with.
+	txCompressed = '~'
+	// txMaxState limits the size of decompressed state.
+	txMaxState = 10 << 20
+)
+
+// txCompress returns data deflated and base64-encoded after txCompressed.
+func txCompress(data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(txCompressed)
+	enc := base64.NewEncoder(base64.RawURLEncoding, &buf)
+	w, _ := flate.NewWriter(enc, flate.BestCompression)
+	w.Write(data)
+	w.Close()
+	enc.Close()
+	return buf.Bytes()
+}
+
+// txDecompress reverses txCompress.
+func txDecompress(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != txCompressed {
+		return nil, errors.New("invalid compressed state")
+	}
+	r := flate.NewReader(base64.NewDecoder(base64.RawURLEncoding, bytes.NewReader(data[1:])))
+	defer r.Close()
+	decompressed, err := io.ReadAll(io.LimitReader(r, txMaxState+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > txMaxState {
+		return nil, errors.New("state is too large")
+	}
+	return decompressed, nil
+}
+
+// seal moves the private fields of the state data of id, which the page or
+// component name renders, into its tx-private field, encrypted with AES-GCM.
+// The name and id are authenticated with it, like txSign signs them.
+func (cfg *txConfig) seal(name, id string, data []byte, private []string) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	sealed := map[string]json.RawMessage{}
+	for _, name := range private {
+		sealed[name] = fields[name]
+		delete(fields, name)
+	}
+	plaintext, err := json.Marshal(sealed)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := txAEAD(cfg.encryptionKeys[0])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	ciphertext := aead.Seal(nonce, nonce, plaintext, []byte(name+"\x00"+id))
+	if fields["tx-private"], err = json.Marshal(base64.RawURLEncoding.EncodeToString(ciphertext)); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// txErrDecrypt is the error of open for private state that none of the
+// encryption keys can decrypt.
+var txErrDecrypt = errors.New("cannot decrypt private state")
+
+// open reverses seal for the state data of id of the page or component name,
+// whose private fields must all be in its tx-private field.
+func (cfg *txConfig) open(name, id string, data []byte, private []string) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, name := range private {
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("private field %s is not encrypted", name)
+		}
+	}
+	var encoded string
+	if err := json.Unmarshal(fields["tx-private"], &encoded); err != nil {
+		return nil, errors.New("private state is missing")
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range cfg.encryptionKeys {
+		aead, err := txAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) < aead.NonceSize() {
+			break
+		}
+		plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], []byte(name+"\x00"+id))
+		if err != nil {
+			continue
+		}
+		sealed := map[string]json.RawMessage{}
+		if err := json.Unmarshal(plaintext, &sealed); err != nil {
+			return nil, err
+		}
+		delete(fields, "tx-private")
+		maps.Copy(fields, sealed)
+		return json.Marshal(fields)
+	}
+	return nil, txErrDecrypt
+}
+
+func txAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func txRandomKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// txSign returns the signature of the state data of id, which the page or
+// component name renders. Signing the name and id too keeps the state of one
+// page or component from being sent as another's.
+func txSign(key []byte, name, id string, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(id))
+	mac.Write([]byte{0})
+	mac.Write(data)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// txDeriveKey derives the key for purpose from a key of WithStateKeys, so
+// that one key can serve several purposes.
+func txDeriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+`
+
+// storeRuntime keeps state on the server for WithStateStore, with the client
+// holding only the session token in its place.
+const storeRuntime = `
+// StateStore keeps the state of pages and components on the server. Every
+// page load starts a session, and the state of each page, layout and
+// component in it is stored by its id. Get returns nil for state that is not
+// stored, such as expired state.
+type StateStore interface {
+	Get(session, id string) ([]byte, error)
+	Put(session, id string, state []byte) error
+	Delete(session, id string) error
+}
+
+// WithStateStore keeps state in store instead of the page, which then only
+// holds a session token. Events on a page whose state is no longer stored
+// reload it, so it starts over from its initial values.
+func WithStateStore(store StateStore) Option {
+	return func(cfg *txConfig) {
+		cfg.store = store
+	}
+}
+
+// loadState returns the stored state of id, whose tx-saved entry is the
+// session token.
+func (tx *TxResponse) loadState(id string, entry json.RawMessage) ([]byte, error) {
+	var session string
+	if err := json.Unmarshal(entry, &session); err != nil {
+		return nil, err
+	}
+	if b, err := base64.RawURLEncoding.DecodeString(session); err != nil || len(b) != 16 {
+		return nil, errors.New("invalid session")
+	}
+	if tx.session != "" && tx.session != session {
+		return nil, errors.New("state of several sessions")
+	}
+	tx.session = session
+	return tx.cfg.store.Get(session, id)
+}
+
+// storeState puts the state data of id in the store and returns its tx-saved
+// entry, the session token. A page load starts a new session.
+func (tx *TxResponse) storeState(id string, data []byte) (json.RawMessage, error) {
+	if tx.session == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		tx.session = base64.RawURLEncoding.EncodeToString(b)
+	}
+	if err := tx.cfg.store.Put(tx.session, id, data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(tx.session)
+}
+
+// deleteState deletes the state the client sent that the response did not
+// render again, except the state of the parent whose slot a component fills.
+func (tx *TxResponse) deleteState(state map[string]any) error {
+	for _, id := range tx.sent {
+		if _, ok := state[id]; ok || id == tx.r.PostFormValue("tx-pid") {
+			continue
+		}
+		if err := tx.cfg.store.Delete(tx.session, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewMemoryStateStore returns a StateStore that keeps state in memory, for a
+// single instance of the program. A session expires ttl after it was last
+// used.
+func NewMemoryStateStore(ttl time.Duration) StateStore {
+	return &txMemoryStore{ttl: ttl, sessions: map[string]*txMemorySession{}}
+}
+
+type txMemoryStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	sessions  map[string]*txMemorySession
+	nextSweep time.Time
+}
+
+type txMemorySession struct {
+	used  time.Time
+	state map[string][]byte
+}
+
+func (s *txMemoryStore) Get(session, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[session]
+	if !ok || time.Since(sess.used) > s.ttl {
+		return nil, nil
+	}
+	sess.used = time.Now()
+	return sess.state[id], nil
+}
+
+func (s *txMemoryStore) Put(session, id string, state []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for key, sess := range s.sessions {
+			if now.Sub(sess.used) > s.ttl {
+				delete(s.sessions, key)
+			}
+		}
+		s.nextSweep = now.Add(s.ttl)
+	}
+	sess, ok := s.sessions[session]
+	if !ok {
+		sess = &txMemorySession{state: map[string][]byte{}}
+		s.sessions[session] = sess
+	}
+	sess.used = now
+	sess.state[id] = state
+	return nil
+}
+
+func (s *txMemoryStore) Delete(session, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[session]; ok {
+		delete(sess.state, id)
+	}
+	return nil
+}
+
+// NewFileStateStore returns a StateStore that keeps state in files under
+// dir, one directory per session. A session expires ttl after it was last
+// written to.
+func NewFileStateStore(dir string, ttl time.Duration) StateStore {
+	return &txFileStore{dir: dir, ttl: ttl}
+}
+
+type txFileStore struct {
+	dir       string
+	ttl       time.Duration
+	mu        sync.Mutex
+	nextSweep time.Time
+}
+
+// path returns the file of the state of id. Sessions are checked tokens,
+// and ids are encoded, so neither can leave dir.
+func (s *txFileStore) path(session, id string) string {
+	return filepath.Join(s.dir, session, base64.RawURLEncoding.EncodeToString([]byte(id)))
+}
+
+func (s *txFileStore) Get(session, id string) ([]byte, error) {
+	info, err := os.Stat(filepath.Join(s.dir, session))
+	if errors.Is(err, fs.ErrNotExist) || err == nil && time.Since(info.ModTime()) > s.ttl {
+		return nil, nil
+	}
+	data, err := os.ReadFile(s.path(session, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *txFileStore) Put(session, id string, state []byte) error {
+	s.sweep()
+	sessionDir := filepath.Join(s.dir, session)
+	if err := os.MkdirAll(sessionDir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path(session, id), state, 0600); err != nil {
+		return err
+	}
+	now := time.Now()
+	return os.Chtimes(sessionDir, now, now)
+}
+
+func (s *txFileStore) Delete(session, id string) error {
+	err := os.Remove(s.path(session, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// sweep removes the expired sessions, at most once per ttl.
+func (s *txFileStore) sweep() {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Before(s.nextSweep) {
+		s.mu.Unlock()
+		return
+	}
+	s.nextSweep = now.Add(s.ttl)
+	s.mu.Unlock()
+
+	entries, _ := os.ReadDir(s.dir)
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && entry.IsDir() && now.Sub(info.ModTime()) > s.ttl {
+			os.RemoveAll(filepath.Join(s.dir, entry.Name()))
+		}
+	}
+}
+`
+
+// recoverRuntime turns panics in handlers into calls to the error handler.
+const recoverRuntime = `
+func txRecover(w http.ResponseWriter, r *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	log.Printf("tmplx: panic serving %s: %v\n%s", r.URL.Path, v, debug.Stack())
+	err, ok := v.(error)
+	if !ok {
+		err = fmt.Errorf("%v", v)
+	}
+	txConfigOf(r).error(w, r, err)
+}
+`
+
+// requestValueRuntime parses the path values of //tx:path variables that are
+// not strings and the values of //tx:query, //tx:header, //tx:cookie and
+// //tx:url variables, and writes //tx:url variables back to the URL.
+const requestValueRuntime = `
+// txParseValue parses s into the variable dst points to. Types other than
+// strings, numbers, booleans and encoding.TextUnmarshaler are decoded from
+// JSON, like handler arguments.
+func txParseValue(s string, dst any) error {
+	if u, ok := dst.(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	v := reflect.ValueOf(dst).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return json.Unmarshal([]byte(s), dst)
+	}
+	return nil
+}
+
+// txBindValues sets the variable dst points to from values, unless there are
+// none or they cannot be parsed. Slices get one element per value.
+func txBindValues(values []string, dst any) {
+	if len(values) == 0 {
+		return
+	}
+	v := reflect.ValueOf(dst).Elem()
+	if _, ok := dst.(encoding.TextUnmarshaler); !ok && v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if txParseValue(value, s.Index(i).Addr().Interface()) != nil {
+				return
+			}
+		}
+		v.Set(s)
+		return
+	}
+	p := reflect.New(v.Type())
+	if txParseValue(values[0], p.Interface()) == nil {
+		v.Set(p.Elem())
+	}
+}
+
+func txCookieValues(r *http.Request, name string) []string {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return nil
+	}
+	return []string{c.Value}
+}
+
+// txFormatValue formats the variable v points to for txParseValue.
+func txFormatValue(v any) string {
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		b, _ := m.MarshalText()
+		return string(b)
+	}
+	rv := reflect.ValueOf(v).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits())
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// txFormatValues formats the variable v points to for txBindValues.
+func txFormatValues(v any) []string {
+	rv := reflect.ValueOf(v).Elem()
+	if _, ok := v.(encoding.TextMarshaler); !ok && rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]string, rv.Len())
+		for i := range values {
+			values[i] = txFormatValue(rv.Index(i).Addr().Interface())
+		}
+		return values
+	}
+	return []string{txFormatValue(v)}
+}
+
+// txPathEscape escapes v for a path wildcard of a URL function. The slashes
+// of a {name...} wildcard are kept.
+func txPathEscape(v string, rest bool) string {
+	if !rest {
+		return url.PathEscape(v)
+	}
+	segs := strings.Split(v, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+// txURLVar is a //tx:url variable of a page.
+type txURLVar struct {
+	name string
+	ptr  any
+	push bool
+}
+
+// txSetURL tells the runtime to write vars into the query string the page
+// was loaded with, which it sends as tx-search. The runtime adds a history
+// entry when a variable marked push changed and replaces the current one
+// otherwise.
+func txSetURL(w http.ResponseWriter, r *http.Request, vars ...txURLVar) {
+	query, _ := url.ParseQuery(strings.TrimPrefix(r.PostFormValue("tx-search"), "?"))
+	header := "Tx-Replace-Url"
+	for _, v := range vars {
+		values := txFormatValues(v.ptr)
+		if v.push && !slices.Equal(query[v.name], values) {
+			header = "Tx-Push-Url"
+		}
+		query[v.name] = values
+	}
+	search := query.Encode()
+	if search != "" {
+		search = "?" + search
+	}
+	w.Header().Set(header, search)
+}
+`
+
+// writeBinding writes the code that sets v from the request when it is a
+// //tx:query, //tx:header, //tx:cookie or //tx:url variable.
+func writeBinding(code *CodeBuilder, v *Var) {
+	if v.Binding == nil {
+		return
+	}
+	var values string
+	switch v.Binding.Name {
+	case CommentQuery:
+		values = fmt.Sprintf("tx_r.URL.Query()[%q]", v.Binding.Value)
+	case CommentHeader:
+		values = fmt.Sprintf("tx_r.Header.Values(%q)", v.Binding.Value)
+	case CommentCookie:
+		values = fmt.Sprintf("txCookieValues(tx_r, %q)", v.Binding.Value)
+	case CommentURL:
+		values = fmt.Sprintf("tx_r.URL.Query()[%q]", v.GoName)
+	}
+	code.write("txBindValues(%s, &tx_saved.%s)\n", values, v.SavedField)
+}
+
+func (c *Compiler) writeRouteType(code *CodeBuilder) {
+	code.write("type TxRoute struct {\n")
+	code.write("Pattern	string\n")
+	code.write("Handler	http.HandlerFunc\n")
+	code.write("}\n")
+}
+
+// writeStateType writes the struct that holds the saved variables of comp.
+// Its JSON names are the variable names, or short keys in their order with
+// the compact state encoding. With //tx:private variables, its txPrivate
+// method lists their JSON names for encodeState to encrypt. It implements
+// txState, whose txVersion method returns the txTypeVersion of the struct,
+// which encodeState saves with the state for unmarshalState to compare.
+func (c *Compiler) writeStateType(code *CodeBuilder, comp *Component) {
+	var private []string
+	code.write("type %s struct {\n", comp.GoName)
+	n := 0
+	for _, v := range comp.Vars {
+		if v.Type == VarTypeState || v.Type == VarTypeProp {
+			name := v.GoName
+			if c.cfg.StateEncoding == StateEncodingCompact {
+				name = strconv.FormatInt(int64(n), 36)
+			}
+			n++
+			code.write("%s %s `json:\"%s\"`\n", v.SavedField, comp.userCode(v.TypePos, v.TypeExpr), name)
+			if v.Private {
+				private = append(private, strconv.Quote(name))
+			}
+		}
+	}
+	code.write("}\n")
+	if len(private) > 0 {
+		code.write("func (*%s) txPrivate() []string {\n", comp.GoName)
+		code.write("return []string{%s}\n", strings.Join(private, ", "))
+		code.write("}\n")
+	}
+	code.write("func (*%s) txName() string {\n", comp.GoName)
+	code.write("return %q\n", comp.Name)
+	code.write("}\n")
+	code.write("func (s *%s) txVersion() string {\n", comp.GoName)
+	code.write("return txTypeVersion(s)\n")
+	code.write("}\n")
+}
+
+func (c *Compiler) writeCompDecls(code *CodeBuilder, comp *Component) {
+	c.writeStateType(code, comp)
+
+	code.write("func render_%s(tx_w *bytes.Buffer, tx_id string", comp.GoName)
+	if len(comp.Slots) > 0 {
+		code.write(", tx_pid, tx_loc string")
+	}
+	if comp.HasChildComps {
+		code.write(", tx_curr_saved map[string]string, tx_next_saved map[string]any, tx *TxResponse")
+	}
+	for _, v := range comp.Vars {
+		if _, ok := comp.UsedVars[v.GoName]; ok {
+			code.write(", %s %s", v.GoName, comp.userCode(v.TypePos, v.TypeExpr))
+		}
+	}
+	for _, f := range comp.Funcs {
+		code.write(", %s, %s_swap string", f.Name, f.Name)
+	}
+	for _, slotName := range comp.Slots {
+		code.write(", tx_render_fill_%s func()", slotName)
+	}
+	code.write(") {\n")
+	comp.RenderFunc.writeTo(code)
+	code.write("}\n")
+	c.writeFills(code, comp)
+
+	if len(comp.CompFills) > 0 {
+		code.write("func render_comp_fill_%s(tx_w *bytes.Buffer, tx_loc string, tx_id string, tx_curr_saved map[string]string", comp.GoName)
+		if comp.CompFillsHasChildComps {
+			code.write(", tx_next_saved map[string]any")
+		}
+		code.write(", tx *TxResponse) {\n")
+		code.write("switch tx_loc {\n")
+		for _, fill := range comp.CompFills {
+			code.write("case \"%s\":\n", fill.Location)
+			code.write("tx_saved := &%s{}\n", fill.ParentComp.GoName)
+			code.write("tx.unmarshalState(tx_id, tx_curr_saved[tx_id], tx_saved)\n")
+			for _, v := range fill.ParentComp.Vars {
+				if v.Type == VarTypeDerived {
+					if _, ok := fill.UsedVars[v.GoName]; ok {
+						code.write("tx_derived_%s := %s\n", v.GoName, v.InitExpr)
+					}
+				}
+			}
+			code.write("render_fill_%s(tx_w", fill.GoName)
+			if fill.HasChildComps {
+				code.write(", tx_id, tx_curr_saved, tx_next_saved, tx")
+			}
+			for _, v := range fill.ParentComp.Vars {
+				if _, ok := fill.UsedVars[v.GoName]; ok {
+					switch v.Type {
+					case VarTypeState, VarTypeProp:
+						code.write(", tx_saved.%s", v.SavedField)
+					case VarTypeDerived:
+						code.write(", tx_derived_%s", v.GoName)
+					}
+				}
+			}
+			code.write(")\n")
+		}
+		code.write("}\n}\n")
+	}
+}
+
+func (c *Compiler) writePageDecls(code *CodeBuilder, page *Component) {
+	if page.URLFunc != "" {
+		writeURLFunc(code, page)
+	}
+	c.writeStateType(code, page)
+
+	code.write("func render_%s(%s, tx *TxResponse", page.GoName, renderWriters(page))
+	if page.HasChildComps {
+		code.write(", tx_curr_saved map[string]string, tx_next_saved map[string]any")
+	}
+	for _, v := range page.Vars {
+		if _, ok := page.UsedVars[v.GoName]; ok {
+			code.write(", %s %s", v.GoName, page.userCode(v.TypePos, v.TypeExpr))
+		}
+	}
+	for _, f := range page.Funcs {
+		code.write(", %s string", f.Name)
+	}
+	code.write(") {\n")
+	page.RenderFunc.writeTo(code)
+	code.write("}\n")
+	c.writeFills(code, page)
+
+	if page.ErrorStatus != 0 {
+		code.write("func serve_%s(tx_w http.ResponseWriter, tx_r *http.Request, tx_err error) {\n", page.GoName)
+		c.writePageLoad(code, page)
+		code.write("}\n")
+	}
+}
+
+// writeLayoutDecls writes the render function of layout and layout_<name>,
+// which page handlers call with a function that renders the page in the
+// layout's <slot>. It restores the layout's state from tx_curr_saved, or
+// initializes it, and runs the handler tx_event.
+func (c *Compiler) writeLayoutDecls(code *CodeBuilder, layout *Component) {
+	c.writeStateType(code, layout)
+
+	code.write("func render_%s(%s, tx *TxResponse, tx_id string, tx_route string", layout.GoName, renderWriters(layout))
+	if layout.HasChildComps {
+		code.write(", tx_curr_saved map[string]string, tx_next_saved map[string]any")
+	}
+	for _, v := range layout.Vars {
+		if _, ok := layout.UsedVars[v.GoName]; ok {
+			code.write(", %s %s", v.GoName, layout.userCode(v.TypePos, v.TypeExpr))
+		}
+	}
+	for _, slotName := range layout.Slots {
+		code.write(", tx_render_fill_%s func()", slotName)
+	}
+	code.write(") {\n")
+	layout.RenderFunc.writeTo(code)
+	code.write("}\n")
+	c.writeFills(code, layout)
+
+	code.write("func layout_%s(%s, tx_r *http.Request, tx_curr_saved map[string]string, tx_next_saved map[string]any, tx *TxResponse, tx_route string, tx_event string", layout.GoName, renderWriters(layout))
+	for _, slotName := range layout.Slots {
+		code.write(", tx_render_fill_%s func()", slotName)
+	}
+	code.write(") {\n")
+	code.write("tx_id := \"%s\"\n", layout.Name)
+	code.write("tx_saved := &%s{}\n", layout.GoName)
+	for _, v := range layout.Vars {
+		if v.Type == VarTypeDerived {
+			code.write("var tx_derived_%s %s\n", v.GoName, layout.userCode(v.TypePos, v.TypeExpr))
+		}
+	}
+	code.write("if tx_curr_saved_str, tx_curr_saved_exist := tx_curr_saved[tx_id]; tx_curr_saved_exist {\n")
+	code.write("tx.unmarshalState(tx_id, tx_curr_saved_str, tx_saved)\n")
+	for _, v := range layout.Vars {
+		if v.Type == VarTypeDerived {
+			code.write("tx_derived_%s = %s\n", v.GoName, v.InitExpr)
+		}
+	}
+	code.write("} else {\n")
+	for _, v := range layout.Vars {
+		switch v.Type {
+		case VarTypeState:
+			if v.InitExpr != "" {
+				code.write("tx_saved.%s = %s\n", v.SavedField, v.InitExpr)
+			}
+			writeBinding(code, v)
+		case VarTypeDerived:
+			code.write("tx_derived_%s = %s\n", v.GoName, v.InitExpr)
+		}
+	}
+	if layout.InitFunc != nil {
+		code.write("%s", layout.InitFunc.Stmts)
+	}
+	code.write("}\n")
+
+	// The event does not run on state that needs a reload.
+	code.write("if !tx.reload {\n")
+	code.write("switch tx_event {\n")
+	for _, f := range slices.Concat(layout.Funcs, layout.AnonFuncs) {
+		if f.Decl.Body == nil {
+			continue
+		}
+		code.write("case \"%s\":\n", f.Name)
+		for _, list := range f.Decl.Type.Params.List {
+			for _, ident := range list.Names {
+				code.write("var %s %s\n", ident.Name, layout.userCode(layout.src.scriptPos(list.Type.Pos()), astToSource(list.Type)))
+				code.write("json.Unmarshal([]byte(tx_r.PostFormValue(\"%s\")), &%s)\n", ident.Name, ident.Name)
+			}
+		}
+		code.write("%s", f.Stmts)
+	}
+	code.write("}\n")
+	code.write("}\n")
+
+	code.write("tx_next_saved[tx_id] = tx_saved\n")
+	callParams := []string{"tx_w1", "tx_w2"}
+	if !layout.isDocument() {
+		callParams = []string{"tx_w"}
+	}
+	callParams = append(callParams, "tx", "tx_id", "tx_route")
+	if layout.HasChildComps {
+		callParams = append(callParams, "tx_curr_saved", "tx_next_saved")
+	}
+	callParams = append(callParams, varParams(layout, layout.UsedVars)...)
+	for _, slotName := range layout.Slots {
+		callParams = append(callParams, "tx_render_fill_"+slotName)
+	}
+	code.write("render_%s(%s)\n", layout.GoName, strings.Join(callParams, ", "))
+	code.write("}\n")
+}
+
+// writeFills writes the render functions of the content comp puts in the
+// slots of its components.
+func (c *Compiler) writeFills(code *CodeBuilder, comp *Component) {
+	for _, fill := range comp.Fills {
+		code.write("func render_fill_%s(tx_w *bytes.Buffer", fill.GoName)
+		if fill.HasChildComps {
+			code.write(", tx_id string, tx_curr_saved map[string]string, tx_next_saved map[string]any, tx *TxResponse")
+		}
+		for _, v := range comp.Vars {
+			if _, ok := fill.UsedVars[v.GoName]; ok {
+				code.write(", %s %s", v.GoName, comp.userCode(v.TypePos, v.TypeExpr))
+			}
+		}
+		code.write(") {\n")
+		fill.RenderFunc.writeTo(code)
+		code.write("}\n")
+	}
+}
+
+func (c *Compiler) writePageRoutes(code *CodeBuilder, page *Component) {
+	middleware := pageMiddleware(page)
+	switch page.ErrorStatus {
+	case 0:
+		code.write("{\n")
+		code.write("Pattern: \"GET %s\",\n", page.Name)
+		writeHandlerStart(code, middleware)
+		code.write("defer txRecover(tx_w, tx_r)\n")
+		c.writePageLoad(code, page)
+		writeHandlerEnd(code, middleware)
+		code.write("},\n")
+	case http.StatusNotFound:
+		// Every GET request no other route matches gets the 404 page.
+		code.write("{\n")
+		code.write("Pattern: \"GET /\",\n")
+		code.write("Handler: func(tx_w http.ResponseWriter, tx_r *http.Request) {\n")
+		code.write("txConfigOf(tx_r).notFound(tx_w, tx_r, errors.New(http.StatusText(http.StatusNotFound)))\n")
+		code.write("},\n")
+		code.write("},\n")
+	}
+
+	pageFuncs := append(page.Funcs, page.AnonFuncs...)
+	for _, f := range pageFuncs {
+		code.write("{\n")
+		code.write("Pattern: \"POST %s%s:%s\",\n", c.cfg.HandlerPrefix, url.PathEscape(page.Name), f.Name)
+		writeHandlerStart(code, middleware)
+		code.write("defer txRecover(tx_w, tx_r)\n")
+		c.writePageRestore(code, page)
+		for _, list := range f.Decl.Type.Params.List {
+			for _, ident := range list.Names {
+				code.write("var %s %s\n", ident.Name, page.userCode(page.src.scriptPos(list.Type.Pos()), astToSource(list.Type)))
+				code.write("json.Unmarshal([]byte(tx_r.PostFormValue(\"%s\")), &%s)\n", ident.Name, ident.Name)
+			}
+		}
+		code.write("%s", f.Stmts)
+		writeURLVars(code, page)
+		c.writePageRender(code, page, "tx_curr_saved", nil, "")
+		writeHandlerEnd(code, middleware)
+		code.write("},\n")
+	}
+
+	// The events of a layout re-render the page they happened on, so each
+	// page has its own routes for them.
+	for _, layout := range page.layouts() {
+		for _, f := range slices.Concat(layout.Funcs, layout.AnonFuncs) {
+			if f.Decl.Body == nil {
+				continue
+			}
+			code.write("{\n")
+			code.write("Pattern: \"POST %s%s%s\",\n", c.cfg.HandlerPrefix, layoutRoute(page, layout), f.Name)
+			writeHandlerStart(code, middleware)
+			code.write("defer txRecover(tx_w, tx_r)\n")
+			c.writePageRestore(code, page)
+			c.writePageRender(code, page, "tx_curr_saved", layout, f.Name)
+			writeHandlerEnd(code, middleware)
+			code.write("},\n")
+		}
+	}
+}
+
+// pageMiddleware returns the //tx:middleware functions of page's layouts,
+// outermost first, followed by its own.
+func pageMiddleware(page *Component) []string {
+	var middleware []string
+	for _, layout := range page.layouts() {
+		middleware = append(middleware, layout.Middleware...)
+	}
+	return append(middleware, page.Middleware...)
+}
+
+// writeHandlerStart writes the start of a route's Handler field, wrapped with
+// middleware if there is any. writeHandlerEnd closes it.
+func writeHandlerStart(code *CodeBuilder, middleware []string) {
+	if len(middleware) == 0 {
+		code.write("Handler: func(tx_w http.ResponseWriter, tx_r *http.Request) {\n")
+		return
+	}
+	code.write("Handler: txChain(func(tx_w http.ResponseWriter, tx_r *http.Request) {\n")
+}
+
+func writeHandlerEnd(code *CodeBuilder, middleware []string) {
+	if len(middleware) == 0 {
+		code.write("},\n")
+		return
+	}
+	code.write("}, %s),\n", strings.Join(middleware, ", "))
+}
+
+// writePageLoad writes the body of the handler that loads page, which
+// initializes its state from the request and renders it.
+func (c *Compiler) writePageLoad(code *CodeBuilder, page *Component) {
+	if page.ErrorStatus != 0 {
+		code.write("tx_w.Header().Set(\"Content-Type\", \"text/html; charset=utf-8\")\n")
+		code.write("tx := newTxResponse(tx_w, tx_r)\n")
+		code.write("tx.status = %d\n", page.ErrorStatus)
+	} else {
+		code.write("tx := newTxResponse(tx_w, tx_r)\n")
+	}
+	code.write("tx_saved := &%s{}\n", page.GoName)
+	for _, v := range page.Vars {
+		if v.parsesPath() {
+			code.write("if err := txParseValue(%s, &tx_saved.%s); err != nil {\n", v.InitExpr, v.SavedField)
+			code.write("tx.cfg.notFound(tx_w, tx_r, err)\n")
+			code.write("return\n")
+			code.write("}\n")
+		} else if v.Type == VarTypeState && v.InitExpr != "" {
+			code.write("tx_saved.%s = %s\n", v.SavedField, v.InitExpr)
+		}
+		if v.Type == VarTypeState {
+			writeBinding(code, v)
+		}
+	}
+	for _, v := range page.Vars {
+		if v.Type == VarTypeDerived {
+			code.write("tx_derived_%s := %s\n", v.GoName, v.InitExpr)
+		}
+	}
+	if page.InitFunc != nil {
+		code.write("%s", page.InitFunc.Stmts)
+	}
+	currSaved := "map[string]string{}"
+	if page.Layout != nil {
+		code.write("tx_curr_saved := %s\n", currSaved)
+		currSaved = "tx_curr_saved"
+	}
+	c.writePageRender(code, page, currSaved, nil, "")
+}
+
+// writeURLVars writes the code that sends the //tx:url variables of page to
+// the runtime after a page event.
+func writeURLVars(code *CodeBuilder, page *Component) {
+	var vars []string
+	for _, v := range page.Vars {
+		if v.Binding != nil && v.Binding.Name == CommentURL {
+			vars = append(vars, fmt.Sprintf("txURLVar{%q, &tx_saved.%s, %t}", v.GoName, v.SavedField, v.Binding.Value == urlPush))
+		}
+	}
+	if len(vars) > 0 {
+		code.write("txSetURL(tx_w, tx_r, %s)\n", strings.Join(vars, ", "))
+	}
+}
+
+// writePageRestore writes the start of a page event handler, which reads the
+// state the client sent.
+func (c *Compiler) writePageRestore(code *CodeBuilder, page *Component) {
+	code.write("tx := newTxResponse(tx_w, tx_r)\n")
+	writeReadState(code)
+	code.write("tx_saved := &%s{}\n", page.GoName)
+	ids := []string{`"page"`}
+	for _, l := range page.layouts() {
+		ids = append(ids, strconv.Quote(l.Name))
+	}
+	code.write("tx.checkState(tx_curr_saved, %s)\n", strings.Join(ids, ", "))
+	code.write("tx.unmarshalState(\"page\", tx_curr_saved[\"page\"], tx_saved)\n")
+	writeReload(code)
+	for _, v := range page.Vars {
+		if v.Type == VarTypeDerived {
+			code.write("tx_derived_%s := %s\n", v.GoName, v.InitExpr)
+		}
+	}
+}
+
+// writeReload writes the return of an event handler whose state needs a
+// reload of the page, before the event runs.
+func writeReload(code *CodeBuilder) {
+	code.write("if tx.reload {\n")
+	code.write("tx.write()\n")
+	code.write("return\n")
+	code.write("}\n")
+}
+
+// writeReadState writes the start of an event handler that reads the state
+// the client sent into tx_curr_saved, answering with 400 if it cannot.
+func writeReadState(code *CodeBuilder) {
+	code.write("tx_curr_saved, tx_err := tx.readState()\n")
+	code.write("if tx_err != nil {\n")
+	code.write("http.Error(tx_w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)\n")
+	code.write("return\n")
+	code.write("}\n")
+}
+
+// writeEncodeState writes the encoding of tx_next_saved into tx_savedBytes.
+// It panics on failure, for txRecover to answer with the error handler.
+func writeEncodeState(code *CodeBuilder) {
+	code.write("tx_savedBytes, tx_err := tx.encodeState(tx_next_saved)\n")
+	code.write("if tx_err != nil {\n")
+	code.write("panic(tx_err)\n")
+	code.write("}\n")
+}
+
+// writePageRender writes the end of a page handler, which renders the page
+// in its layouts and writes the response. currSaved is the state the client
+// sent, and event the handler of layout to run, if layout is not nil.
+func (c *Compiler) writePageRender(code *CodeBuilder, page *Component, currSaved string, layout *Component, event string) {
+	code.write("tx_next_saved := map[string]any{\"page\": tx_saved}\n")
+	code.write("var tx_buf1, tx_buf2 bytes.Buffer\n")
+	callParams := []string{"&tx_buf1", "&tx_buf2"}
+	if page.Layout != nil {
+		callParams = []string{"&tx_buf2"}
+	}
+	callParams = append(callParams, "tx")
+	if page.HasChildComps {
+		callParams = append(callParams, currSaved, "tx_next_saved")
+	}
+	callParams = append(callParams, varParams(page, page.UsedVars)...)
+	for _, f := range page.Funcs {
+		callParams = append(callParams, fmt.Sprintf("\"%s\"", url.PathEscape(page.Name)+":"+f.Name))
+	}
+	call := fmt.Sprintf("render_%s(%s)\n", page.GoName, strings.Join(callParams, ", "))
+
+	layouts := page.layouts()
+	for i := len(layouts) - 1; i >= 0; i-- {
+		l := layouts[i]
+		writers := "&tx_buf2"
+		if l.isDocument() {
+			writers = "&tx_buf1, &tx_buf2"
+		}
+		lEvent := ""
+		if l == layout {
+			lEvent = event
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "layout_%s(%s, tx_r, %s, tx_next_saved, tx, \"%s\", \"%s\"", l.GoName, writers, currSaved, layoutRoute(page, l), lEvent)
+		for _, slotName := range l.Slots {
+			switch {
+			case slotName != headSlotName:
+				fmt.Fprintf(&b, ", func() {\n%s}", call)
+			case page.HeadFill == nil:
+				b.WriteString(", nil")
+			default:
+				fillParams := []string{"&tx_buf1"}
+				if page.HeadFill.HasChildComps {
+					fillParams = append(fillParams, "\"page\"", currSaved, "tx_next_saved", "tx")
+				}
+				fillParams = append(fillParams, varParams(page, page.HeadFill.UsedVars)...)
+				fmt.Fprintf(&b, ", func() {\nrender_fill_%s(%s)\n}", page.HeadFill.GoName, strings.Join(fillParams, ", "))
+			}
+		}
+		b.WriteString(")\n")
+		call = b.String()
+	}
+
+	code.write("%s", call)
+	writeEncodeState(code)
+	code.write("tx.write(tx_buf1.Bytes(), tx_savedBytes, tx_buf2.Bytes())\n")
+}
+
+// varParams returns the arguments that pass the variables in used of a page
+// or layout to a render function.
+func varParams(comp *Component, used map[string]struct{}) []string {
+	params := []string{}
+	for _, v := range comp.Vars {
+		if _, ok := used[v.GoName]; ok {
+			switch v.Type {
+			case VarTypeState:
+				params = append(params, "tx_saved."+v.SavedField)
+			case VarTypeDerived:
+				params = append(params, "tx_derived_"+v.GoName)
+			}
+		}
+	}
+	return params
+}
+
+// renderWriters returns the buffer parameters of the render function of a
+// page or layout. One that renders a whole document writes the part before
+// the tx-saved script to tx_w1 and the rest to tx_w2.
+func renderWriters(comp *Component) string {
+	if comp.isDocument() {
+		return "tx_w1 *bytes.Buffer, tx_w2 *bytes.Buffer"
+	}
+	return "tx_w *bytes.Buffer"
+}
+
+// layoutRoute returns the prefix of the routes of layout's handlers on page.
+func layoutRoute(page, layout *Component) string {
+	return url.PathEscape(page.Name) + ":" + url.PathEscape(layout.Name) + ":"
+}
+
+func (c *Compiler) writeCompRoutes(code *CodeBuilder, comp *Component) {
+	compFuncs := append(comp.Funcs, comp.AnonFuncs...)
+	for _, f := range compFuncs {
+		if f.Decl.Body == nil {
+			continue
+		}
+		code.write("{\n")
+		code.write("Pattern: \"POST %s%s:%s\",\n", c.cfg.HandlerPrefix, comp.Name, f.Name)
+		writeHandlerStart(code, comp.RouteMiddleware)
+		code.write("defer txRecover(tx_w, tx_r)\n")
+		code.write("tx := newTxResponse(tx_w, tx_r)\n")
+		code.write("tx_r.ParseForm()\n")
+		code.write("tx_id := tx_r.PostFormValue(\"tx-swap\")\n")
+		if len(comp.Slots) > 0 {
+			code.write("tx_pid := tx_r.PostFormValue(\"tx-pid\")\n")
+			code.write("tx_loc := tx_r.PostFormValue(\"tx-loc\")\n")
+		}
+		writeReadState(code)
+		code.write("tx_next_saved := map[string]any{}\n")
+		code.write("tx_saved := &%s{}\n", comp.GoName)
+		code.write("tx.checkState(tx_curr_saved, tx_id)\n")
+		code.write("tx.unmarshalState(tx_id, tx_curr_saved[tx_id], tx_saved)\n")
+		writeReload(code)
+		for _, v := range comp.Vars {
+			if v.Type == VarTypeDerived {
+				code.write("tx_derived_%s := %s\n", v.GoName, v.InitExpr)
+			}
+		}
+		for _, list := range f.Decl.Type.Params.List {
+			for _, ident := range list.Names {
+				code.write("var %s %s\n", ident.Name, comp.userCode(comp.src.scriptPos(list.Type.Pos()), astToSource(list.Type)))
+				code.write("json.Unmarshal([]byte(tx_r.PostFormValue(\"%s\")), &%s)\n", ident.Name, ident.Name)
+			}
+		}
+		code.write("%s", f.Stmts)
+		code.write("tx_next_saved[tx_id] = tx_saved\n")
+		code.write("var tx_buf bytes.Buffer\n")
+		callParams := []string{"&tx_buf", "tx_id"}
+		if len(comp.Slots) > 0 {
+			callParams = append(callParams, "tx_pid", "tx_loc")
+		}
+		if comp.HasChildComps {
+			callParams = append(callParams, "tx_curr_saved", "tx_next_saved", "tx")
+		}
+		for _, v := range comp.Vars {
+			if _, ok := comp.UsedVars[v.GoName]; ok {
+				switch v.Type {
+				case VarTypeState, VarTypeProp:
+					callParams = append(callParams, "tx_saved."+v.SavedField)
+				case VarTypeDerived:
+					callParams = append(callParams, "tx_derived_"+v.GoName)
+				}
+			}
+		}
+		for _, f := range comp.Funcs {
+			callParams = append(callParams, fmt.Sprintf("\"%s\"", comp.Name+":"+f.Name), "tx_id")
+		}
+		code.write("render_%s(%s", comp.GoName, strings.Join(callParams, ", "))
+		for _, slotName := range comp.Slots {
+			if len(comp.CompFills) == 0 {
+				code.write(", nil")
+			} else {
+				code.write(", func() {\n")
+				code.write("render_comp_fill_%s(&tx_buf, tx_loc+\"_%s\", tx_pid, tx_curr_saved", comp.GoName, slotName)
+				if comp.CompFillsHasChildComps {
+					code.write(", tx_next_saved")
+				}
+				code.write(", tx)\n")
+				code.write("}")
+			}
+		}
+		code.write(")\n")
+		writeEncodeState(code)
+		code.write("tx.write(tx_buf.Bytes(), []byte(\"<script id=\\\"tx-saved\\\" type=\\\"application/json\\\">\"), tx_savedBytes, []byte(\"</script>\"))\n")
+		writeHandlerEnd(code, comp.RouteMiddleware)
+		code.write("},\n")
+	}
+}
+
+// format formats code, the file name, and completes its imports.
+// declsStart is the offset of the first declaration after the imports.
+func (c *Compiler) format(name string, code *CodeBuilder, declsStart int) (outputFile, error) {
+	marked := code.String()
+	data := []byte(userCodeMark.ReplaceAllString(marked, ""))
+	formatted, err := imports.Process(filepath.Join(c.outputDir(), name), data, nil)
+	if err != nil {
+		lines := strings.Split(string(data), "\n")
+		start, end := 0, len(lines)
+		var errs scanner.ErrorList
+		if errors.As(err, &errs) && len(errs) > 0 {
+			errLine := errs[0].Pos.Line
+			start = max(errLine-6, 0)
+			end = min(errLine+5, len(lines))
+		}
+		var context strings.Builder
+		for i := start; i < end; i++ {
+			fmt.Fprintf(&context, "\n%d: %s", i+1, lines[i])
+		}
+		return outputFile{}, fmt.Errorf("format generated code: %w%s", err, context.String())
+	}
+
+	return outputFile{
+		name:   name,
+		source: c.lineDirectives(formatted, marked, declsStart, name),
+		decls:  marked[declsStart:],
+	}, nil
+}
@@ -0,0 +1,223 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// generatedImports are the packages generated code refers to, by the names
+// it uses for them. A script that imports another package by one of these
+// names would change what the generated code calls, such as math/rand for
+// the crypto/rand that makes encryption nonces, so it is an error.
+var generatedImports = map[string]string{
+	"aes":      "crypto/aes",
+	"base64":   "encoding/base64",
+	"bytes":    "bytes",
+	"cipher":   "crypto/cipher",
+	"context":  "context",
+	"debug":    "runtime/debug",
+	"encoding": "encoding",
+	"errors":   "errors",
+	"filepath": "path/filepath",
+	"flate":    "compress/flate",
+	"fmt":      "fmt",
+	"fs":       "io/fs",
+	"hmac":     "crypto/hmac",
+	"html":     "html",
+	"http":     "net/http",
+	"io":       "io",
+	"json":     "encoding/json",
+	"log":      "log",
+	"maps":     "maps",
+	"os":       "os",
+	"rand":     "crypto/rand",
+	"reflect":  "reflect",
+	"sha256":   "crypto/sha256",
+	"slices":   "slices",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"sync":     "sync",
+	"time":     "time",
+	"url":      "net/url",
+}
+
+// checkImports reports imports in tmplx scripts that cannot be found, are
+// not used by their page, layout or component, or take a name another page,
+// layout, component or generated code uses for a different package.
+// Generated files hold the imports of every page, layout and component whose
+// code they copy, so names must be unique across the project.
+func (c *Compiler) checkImports() *multiError {
+	merr := newMultiError()
+	comps := c.sources()
+	if c.cfg.TypeCheck {
+		merr.concat(c.resolveImports(comps))
+		if !merr.empty() {
+			return merr
+		}
+	}
+
+	type imported struct {
+		comp *Component
+		path string
+	}
+	byName := map[string]imported{}
+	for name, p := range generatedImports {
+		byName[name] = imported{path: p}
+	}
+	for _, comp := range comps {
+		for _, spec := range comp.Imports {
+			p := importPath(spec)
+			name, known := c.importName(spec)
+			if name == "_" || name == "." {
+				continue
+			}
+
+			// An assumed name may be wrong, so only a known one can be
+			// reported as unused.
+			if _, used := comp.UsedPkgs[name]; !used && known {
+				if spec.Name != nil {
+					merr.append(comp.errf(comp.src.scriptRange(spec), CodeUnusedImport, "%q imported as %s and not used", p, name))
+				} else {
+					merr.append(comp.errf(comp.src.scriptRange(spec), CodeUnusedImport, "%q imported and not used", p))
+				}
+			}
+
+			prev, ok := byName[name]
+			switch {
+			case !ok:
+				byName[name] = imported{comp: comp, path: p}
+			case prev.path == p:
+			case prev.comp == nil:
+				merr.append(comp.errf(comp.src.scriptRange(spec), CodeImportCollision, "%q imported as %s collides with %q, which generated code uses by that name (use an alias)", p, name, prev.path))
+			default:
+				merr.append(comp.errf(comp.src.scriptRange(spec), CodeImportCollision, "%q imported as %s collides with %q imported as %s in %s (use an alias)", p, name, prev.path, name, prev.comp.FilePath))
+			}
+		}
+	}
+	return merr
+}
+
+// resolveImports looks up the packages imported by comps that were not
+// looked up before in the module of the output package, and reports the
+// ones that cannot be found.
+func (c *Compiler) resolveImports(comps []*Component) *multiError {
+	merr := newMultiError()
+	type importSpec struct {
+		comp *Component
+		spec *ast.ImportSpec
+	}
+	specsByPath := map[string][]importSpec{}
+	paths := []string{}
+	for _, comp := range comps {
+		for _, spec := range comp.Imports {
+			p := importPath(spec)
+			if _, ok := c.packageNames[p]; ok {
+				continue
+			}
+			if _, ok := specsByPath[p]; !ok {
+				paths = append(paths, p)
+			}
+			specsByPath[p] = append(specsByPath[p], importSpec{comp: comp, spec: spec})
+		}
+	}
+	if len(paths) == 0 {
+		return merr
+	}
+
+	outputDir, err := filepath.Abs(c.outputDir())
+	if err != nil {
+		merr.append(Diagnostic{Code: CodeUnknownImport, Message: fmt.Sprintf("resolve imports: %v", err)})
+		return merr
+	}
+	// Patterns such as "std" or "./..." are not import paths, and go list
+	// would expand them.
+	patterns := slices.DeleteFunc(slices.Clone(paths), func(p string) bool {
+		switch p {
+		case "", "all", "std", "cmd", "tool":
+			return true
+		}
+		return strings.Contains(p, "...") || strings.HasPrefix(p, ".") || strings.HasPrefix(p, "/")
+	})
+	found := map[string]*packages.Package{}
+	if len(patterns) > 0 {
+		// Only the module graph on disk is searched, so a typo does not
+		// make every build ask the module proxy.
+		pkgs, err := packages.Load(&packages.Config{
+			Mode: packages.NeedName,
+			Dir:  existingDir(outputDir),
+			Env:  append(os.Environ(), "GOPROXY=off"),
+		}, patterns...)
+		if err != nil {
+			merr.append(Diagnostic{Code: CodeUnknownImport, Message: fmt.Sprintf("resolve imports: %v", err)})
+			return merr
+		}
+		for _, pkg := range pkgs {
+			found[pkg.PkgPath] = pkg
+		}
+	}
+
+	for _, p := range paths {
+		pkg, ok := found[p]
+		if ok && pkg.Name != "" {
+			c.packageNames[p] = pkg.Name
+			continue
+		}
+		msg := "cannot find package"
+		if ok && len(pkg.Errors) > 0 {
+			msg = pkg.Errors[0].Msg
+		}
+		for _, s := range specsByPath[p] {
+			merr.append(s.comp.errf(s.comp.src.scriptRange(s.spec.Path), CodeUnknownImport, "unknown package %q: %s", p, msg))
+		}
+	}
+	return merr
+}
+
+// importName returns the name spec's package is referred to by, and whether
+// it is known rather than assumed from the import path.
+func (c *Compiler) importName(spec *ast.ImportSpec) (string, bool) {
+	if spec.Name != nil {
+		return spec.Name.Name, true
+	}
+	p := importPath(spec)
+	if name, ok := c.packageNames[p]; ok {
+		return name, true
+	}
+	return assumedPackageName(p), false
+}
+
+func importPath(spec *ast.ImportSpec) string {
+	p, _ := strconv.Unquote(spec.Path.Value)
+	return p
+}
+
+// assumedPackageName guesses the name of the package at importPath the way
+// goimports does: the last element of the path, skipping a major version
+// suffix, without a "go-" prefix and cut at the first character that cannot
+// be in an identifier.
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			if dir := path.Dir(importPath); dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
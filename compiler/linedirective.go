@@ -0,0 +1,186 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lineFile returns how line directives in the output files refer to the
+// page or component at filePath: relative to the output directory when
+// SourceDir is set, and as filePath otherwise.
+func (c *Compiler) lineFile(filePath string) string {
+	if c.cfg.SourceDir == "" {
+		return filePath
+	}
+	file, err := filepath.Abs(filepath.Join(c.cfg.SourceDir, filepath.FromSlash(filePath)))
+	if err != nil {
+		return filePath
+	}
+	outDir, err := filepath.Abs(c.outputDir())
+	if err != nil {
+		return file
+	}
+	if rel, err := filepath.Rel(outDir, file); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
+
+// lineDirectives adds //line directives to formatted, the output file name,
+// so that each line holding code copied from a page or component refers to
+// the line the code was copied from, and the lines after it refer back to
+// name. marked is the file before formatting, with the marks added by
+// userCode, and declsStart the offset of its first declaration after the
+// imports. formatted is returned as is when SourceDir is not set,
+// or when its declarations do not have the same tokens as marked.
+func (c *Compiler) lineDirectives(formatted []byte, marked string, declsStart int, name string) []byte {
+	if c.cfg.SourceDir == "" {
+		return formatted
+	}
+
+	type mark struct {
+		off int
+		pos string
+	}
+	var marks []mark
+	var src strings.Builder
+	last := 0
+	for _, m := range userCodeMark.FindAllStringSubmatchIndex(marked, -1) {
+		src.WriteString(marked[last:m[0]])
+		marks = append(marks, mark{off: src.Len(), pos: marked[m[2]:m[3]]})
+		last = m[1]
+	}
+	src.WriteString(marked[last:])
+
+	// Formatting only changes the imports and the space between tokens, so
+	// the declarations are the last tokens of both.
+	srcToks := goTokens([]byte(src.String()))
+	fmtToks := goTokens(formatted)
+	first := sort.Search(len(srcToks), func(i int) bool { return srcToks[i].off >= declsStart })
+	shift := len(fmtToks) - len(srcToks)
+	if first+shift < 0 {
+		return formatted
+	}
+	for i := first; i < len(srcToks); i++ {
+		if srcToks[i].tok != fmtToks[i+shift].tok {
+			return formatted
+		}
+	}
+
+	lines := bytes.SplitAfter(formatted, []byte("\n"))
+	lineStarts := make([]int, len(lines))
+	for i, off := 1, 0; i < len(lines); i++ {
+		off += len(lines[i-1])
+		lineStarts[i] = off
+	}
+	lineOf := func(off int) int {
+		return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > off }) - 1
+	}
+
+	// A directive cannot go before a line that starts inside a raw string.
+	inToken := make([]bool, len(lines))
+	for _, t := range fmtToks {
+		for l := lineOf(t.off) + 1; l <= lineOf(t.end-1); l++ {
+			inToken[l] = true
+		}
+	}
+
+	type event struct {
+		line int
+		// file is empty for the end of copied code.
+		file     string
+		fileLine int
+	}
+	events := []event{}
+	for _, m := range marks {
+		i := sort.Search(len(srcToks), func(i int) bool { return srcToks[i].off >= m.off })
+		if i >= len(srcToks) {
+			continue
+		}
+		e := event{line: lineOf(fmtToks[i+shift].off)}
+		if m.pos != "" {
+			// m.pos is file:line:column.
+			pos := m.pos[:strings.LastIndex(m.pos, ":")]
+			sep := strings.LastIndex(pos, ":")
+			e.file = pos[:sep]
+			e.fileLine, _ = strconv.Atoi(pos[sep+1:])
+		}
+		events = append(events, e)
+	}
+
+	var out bytes.Buffer
+	outLine := 1
+	mapped, inCopy := false, false
+	var currFile string
+	var currLine int
+	next := 0
+	for l, line := range lines {
+		var start *event
+		continues := inCopy
+		for ; next < len(events) && events[next].line <= l; next++ {
+			e := &events[next]
+			if start == nil && e.file != "" {
+				start = e
+			}
+			inCopy = e.file != ""
+		}
+
+		switch {
+		case inToken[l]:
+		case start != nil:
+			if !mapped || start.file != currFile || start.fileLine != currLine {
+				fmt.Fprintf(&out, "//line %s:%d\n", start.file, start.fileLine)
+				outLine++
+				mapped = true
+				currFile, currLine = start.file, start.fileLine
+			}
+		case continues:
+		case mapped:
+			fmt.Fprintf(&out, "//line %s:%d\n", name, outLine+1)
+			outLine++
+			mapped = false
+		}
+
+		out.Write(line)
+		outLine++
+		if mapped {
+			currLine++
+		}
+	}
+	return out.Bytes()
+}
+
+type goToken struct {
+	off, end int
+	tok      token.Token
+}
+
+// goTokens returns the tokens of src, without comments and the semicolons
+// inserted at line ends.
+func goTokens(src []byte) []goToken {
+	var s scanner.Scanner
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	s.Init(file, src, nil, 0)
+	var toks []goToken
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return toks
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		off := file.Offset(pos)
+		end := off + len(lit)
+		if lit == "" {
+			end = off + len(tok.String())
+		}
+		toks = append(toks, goToken{off: off, end: end, tok: tok})
+	}
+}
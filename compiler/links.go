@@ -0,0 +1,129 @@
+package compiler
+
+import (
+	"go/token"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// urlFuncName returns the name of the function that builds the URL of the
+// page at route: URLExamplesState for /examples/state, URLUser for
+// /user/{id} and URLBlogIndex for /blog/{$}.
+func urlFuncName(route string) string {
+	var b strings.Builder
+	b.WriteString("URL")
+	for _, seg := range strings.Split(route, "/") {
+		if strings.HasPrefix(seg, "{") {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(seg, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			r, size := utf8.DecodeRuneInString(word)
+			b.WriteRune(unicode.ToUpper(r))
+			b.WriteString(word[size:])
+		}
+	}
+	if strings.HasSuffix(route, "{$}") {
+		b.WriteString("Index")
+	}
+	return b.String()
+}
+
+// setURLFuncs names the URL function of every page but the error pages. A
+// page whose name another page already has gets none.
+func (c *Compiler) setURLFuncs() *multiError {
+	merr := newMultiError()
+	taken := map[string]*Component{}
+	for _, page := range c.pages {
+		page.URLFunc = ""
+		if page.ErrorStatus != 0 {
+			continue
+		}
+		name := urlFuncName(page.Name)
+		if other, ok := taken[name]; ok {
+			merr.append(page.warnf(srcRange{}, CodeURLFuncName, "no %s is generated for %s, the name is taken by %s", name, page.Name, other.FilePath))
+			continue
+		}
+		taken[name] = page
+		page.URLFunc = name
+	}
+	return merr
+}
+
+// writeURLFunc writes the URL function of page, which takes the route's
+// wildcards in order and path-escapes them.
+func writeURLFunc(code *CodeBuilder, page *Component) {
+	route := strings.TrimSuffix(page.Name, "{$}")
+	var params, parts []string
+	last := 0
+	for _, m := range wildcardPattern.FindAllStringSubmatchIndex(route, -1) {
+		parts = append(parts, strconv.Quote((&url.URL{Path: route[last:m[0]]}).EscapedPath()))
+		name := route[m[2]:m[3]]
+		if token.IsKeyword(name) {
+			name += "_"
+		}
+		params = append(params, name+" string")
+		parts = append(parts, "txPathEscape("+name+", "+strconv.FormatBool(strings.HasSuffix(route[m[0]:m[1]], "...}"))+")")
+		last = m[1]
+	}
+	if last < len(route) {
+		parts = append(parts, strconv.Quote((&url.URL{Path: route[last:]}).EscapedPath()))
+	}
+
+	code.write("// %s returns the URL of the %s page.\n", page.URLFunc, page.Name)
+	code.write("// It does not include the prefix of WithPrefix.\n")
+	code.write("func %s(%s) string {\n", page.URLFunc, strings.Join(params, ", "))
+	code.write("return %s\n", strings.Join(parts, " + "))
+	code.write("}\n")
+}
+
+// checkLinks warns about the literal <a href> paths of every page, layout
+// and component that no page route matches. Links to files, such as
+// /report.pdf, and to other hosts are not checked. Routes are matched as
+// they are, so links that include the prefix of WithPrefix get a warning.
+func (c *Compiler) checkLinks() *multiError {
+	merr := newMultiError()
+	mux := http.NewServeMux()
+	for _, page := range c.pages {
+		if page.ErrorStatus != 0 {
+			continue
+		}
+		if !handleRoute(mux, "GET "+page.Name) {
+			return merr
+		}
+	}
+
+	for _, comp := range c.sources() {
+		for _, l := range comp.links {
+			u, err := url.Parse(l.href)
+			if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+				continue
+			}
+			if path.Ext(u.Path) != "" || strings.HasPrefix(u.Path, c.cfg.HandlerPrefix) {
+				continue
+			}
+			if _, pattern := mux.Handler(&http.Request{Method: http.MethodGet, URL: u}); pattern == "" {
+				merr.append(comp.warnf(l.at, CodeUnknownRoute, "<a href=\"%s\">: no page route matches %s", l.href, u.Path))
+			}
+		}
+	}
+	return merr
+}
+
+// handleRoute registers pattern on mux and reports whether it could, which
+// it cannot when pattern conflicts with one already registered.
+func handleRoute(mux *http.ServeMux, pattern string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	mux.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+	return true
+}
@@ -0,0 +1,73 @@
+package compiler
+
+import (
+	"slices"
+	"strings"
+)
+
+// setRouteMiddleware sets the middleware that wraps the event routes of each
+// component: the //tx:middleware of the pages that render it, directly or
+// through layouts and other components. The routes are shared by those
+// pages, so they run only the middleware all of them start with, and a page
+// whose middleware goes further gets a warning.
+func (c *Compiler) setRouteMiddleware() *multiError {
+	merr := newMultiError()
+	users := map[*Component][]*Component{}
+	for _, page := range c.pages {
+		for _, comp := range c.rendered(page) {
+			users[comp] = append(users[comp], page)
+		}
+	}
+
+	for _, comp := range c.components {
+		comp.RouteMiddleware, comp.routeMiddlewarePage = nil, nil
+		pages := users[comp]
+		if len(pages) == 0 {
+			continue
+		}
+		shared := pageMiddleware(pages[0])
+		for _, page := range pages[1:] {
+			middleware := pageMiddleware(page)
+			n := 0
+			for n < len(shared) && n < len(middleware) && stripUserCode(shared[n]) == stripUserCode(middleware[n]) {
+				n++
+			}
+			shared = shared[:n]
+		}
+		for _, page := range pages {
+			if middleware := pageMiddleware(page); len(middleware) > len(shared) {
+				merr.append(comp.warnf(srcRange{}, CodeRouteMiddleware, "the event routes of %s do not run %s, which %s adds to the pages it shares them with", comp.Name, stripUserCode(strings.Join(middleware[len(shared):], " ")), page.Name))
+				break
+			}
+		}
+		if len(shared) > 0 {
+			comp.RouteMiddleware, comp.routeMiddlewarePage = shared, pages[0]
+		}
+	}
+	return merr
+}
+
+// rendered returns the components page renders, directly or through its
+// layouts and other components.
+func (c *Compiler) rendered(page *Component) []*Component {
+	var comps []*Component
+	queue := append([]*Component{page}, page.layouts()...)
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for name := range parent.ChildComps {
+			child, ok := c.componentsByName[name]
+			if !ok || slices.Contains(comps, child) {
+				continue
+			}
+			comps = append(comps, child)
+			queue = append(queue, child)
+		}
+	}
+	return comps
+}
+
+// stripUserCode returns code without the marks added by userCode.
+func stripUserCode(code string) string {
+	return userCodeMark.ReplaceAllString(code, "")
+}
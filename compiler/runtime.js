@@ -10,6 +10,12 @@ document.addEventListener('DOMContentLoaded', function() {
     }
   }
 
+  const replaceDocument = (html) => {
+    document.open()
+    document.write(html)
+    document.close()
+  }
+
   const send = async (cn, fun, params) => {
     const txSwap = cn.getAttribute("tx-swap") ?? ""
     if (txSwap !== "") {
@@ -17,28 +23,47 @@ document.addEventListener('DOMContentLoaded', function() {
     }
 
     const txParent = cn.getAttribute("tx-pid")
+    const sent = {}
     for (let key in state) {
       if (key.startsWith(txSwap)) {
-        params.append(key, JSON.stringify(state[key]))
+        sent[key] = state[key]
       }
     }
     if (txParent !== null && state[txParent] !== undefined) {
-      params.append(txParent, JSON.stringify(state[txParent]))
+      sent[txParent] = state[txParent]
     }
+    params.append("tx-state", JSON.stringify(sent))
 
     for (let attr of cn.attributes) {
       if (attr.name === 'tx-loc' || attr.name === 'tx-pid') {
         params.append(attr.name, attr.value)
       }
     }
+    params.append("tx-search", location.search)
 
     const res = await fetch("TX_HANDLER_PREFIX" + fun, { method: 'POST', headers: { 'Content-Type': 'application/x-www-form-urlencoded' }, body: params.toString() })
+    const redirect = res.headers.get('tx-redirect')
+    if (redirect !== null) {
+      location.assign(redirect)
+      return
+    }
+    if (res.headers.get('tx-reload') !== null) {
+      location.reload()
+      return
+    }
     const html = await res.text()
 
-    if (txSwap === '') {
-      document.open()
-      document.write(html)
-      document.close()
+    const pushUrl = res.headers.get('tx-push-url')
+    const replaceUrl = res.headers.get('tx-replace-url')
+    if (pushUrl !== null && pushUrl !== location.search) {
+      history.replaceState({ tx: true }, '')
+      history.pushState({ tx: true }, '', location.pathname + pushUrl + location.hash)
+    } else if (replaceUrl !== null && replaceUrl !== location.search) {
+      history.replaceState({ tx: true }, '', location.pathname + replaceUrl + location.hash)
+    }
+
+    if (txSwap === '' || !res.ok) {
+      replaceDocument(html)
       return
     }
 
@@ -130,4 +155,10 @@ document.addEventListener('DOMContentLoaded', function() {
     })
   }).observe(document.documentElement, { childList: true, subtree: true })
   addHandler(document.documentElement)
+
+  window.addEventListener('popstate', async (e) => {
+    if (!e.state?.tx) return
+    const res = await fetch(location.href)
+    replaceDocument(await res.text())
+  })
 });
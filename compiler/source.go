@@ -0,0 +1,423 @@
+package compiler
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// posAttr is added to every start tag before parsing so that each parsed
+// element can be traced back to its tag. parseHTML removes it again.
+const posAttr = "tx-pos"
+
+// scriptPrefix turns the body of a <script type="text/tmplx"> into a Go file.
+const scriptPrefix = "package p\n"
+
+// source is a page or component file and the positions of the nodes parsed
+// from it.
+type source struct {
+	file   *token.File
+	tags   map[*html.Node]*tagSpan
+	texts  map[*html.Node]textSpan
+	copies map[*html.Node]*html.Node
+
+	// script is the Go file parsed from the <script type="text/tmplx">, and
+	// scriptOff the offset of its body in file.
+	script    *token.File
+	scriptOff int
+}
+
+// span is raw source text starting at byte offset off.
+type span struct {
+	off int
+	raw string
+}
+
+// tagSpan is a start tag and the attributes written in it.
+type tagSpan struct {
+	span
+	attrs []attrSpan
+}
+
+type attrSpan struct {
+	key string
+	off int
+	val span
+}
+
+type textToken struct {
+	span
+	data string
+}
+
+// textSpan is a text node found at byte skip of the data of a text token.
+type textSpan struct {
+	span
+	skip int
+}
+
+// parseHTML parses data as a document, or as the children of <body> when
+// fragment is set, and records where each element and text node came from.
+func parseHTML(name string, data []byte, fragment bool) ([]*html.Node, *source, error) {
+	data = normalizeNewlines(data)
+	src := &source{
+		file:   token.NewFileSet().AddFile(name, -1, len(data)),
+		tags:   map[*html.Node]*tagSpan{},
+		texts:  map[*html.Node]textSpan{},
+		copies: map[*html.Node]*html.Node{},
+	}
+	src.file.SetLinesForContent(data)
+
+	var buf bytes.Buffer
+	tags := []*tagSpan{}
+	texts := []textToken{}
+	z := html.NewTokenizer(bytes.NewReader(data))
+	for off := 0; ; {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		// Text unescapes in place, so copy the raw token first.
+		raw := string(z.Raw())
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tag := scanTag(off, raw)
+			nameEnd := 1 + strings.IndexAny(raw[1:]+">", " \t\n\f\r/>")
+			fmt.Fprintf(&buf, `%s %s="%d"%s`, raw[:nameEnd], posAttr, len(tags), raw[nameEnd:])
+			tags = append(tags, tag)
+		case html.TextToken:
+			texts = append(texts, textToken{span: span{off: off, raw: raw}, data: string(z.Text())})
+			buf.WriteString(raw)
+		default:
+			buf.WriteString(raw)
+		}
+		off += len(raw)
+	}
+
+	var nodes []*html.Node
+	if fragment {
+		var err error
+		nodes, err = html.ParseFragment(&buf, &html.Node{
+			Data:     "body",
+			DataAtom: atom.Body,
+			Type:     html.ElementNode,
+		})
+		if err != nil {
+			return nil, src, err
+		}
+	} else {
+		doc, err := html.Parse(&buf)
+		if err != nil {
+			return nil, src, err
+		}
+		nodes = []*html.Node{doc}
+	}
+
+	next := 0
+	for _, n := range nodes {
+		src.trace(n, tags, texts, &next)
+	}
+	return nodes, src, nil
+}
+
+// trace strips posAttr from n and its descendants and records their
+// positions. Text nodes are matched against texts in document order; next
+// is the first text token not yet matched. Each element moves next to the
+// text after its start tag, back too when the parser moved it, such as a
+// <script> before <html> that ends up in <head>.
+func (src *source) trace(n *html.Node, tags []*tagSpan, texts []textToken, next *int) {
+	switch n.Type {
+	case html.ElementNode:
+		i := slices.IndexFunc(n.Attr, func(attr html.Attribute) bool {
+			return attr.Key == posAttr && attr.Namespace == ""
+		})
+		if i >= 0 {
+			idx, err := strconv.Atoi(n.Attr[i].Val)
+			n.Attr = slices.Delete(n.Attr, i, i+1)
+			if err == nil && idx < len(tags) {
+				tag := tags[idx]
+				src.tags[n] = tag
+				*next, _ = slices.BinarySearchFunc(texts, tag.off+len(tag.raw), func(text textToken, off int) int {
+					return cmp.Compare(text.off, off)
+				})
+			}
+		}
+	case html.TextNode:
+		// Whitespace matches anywhere and is never reported on.
+		if strings.TrimSpace(n.Data) == "" {
+			break
+		}
+		for j := *next; j < len(texts); j++ {
+			if k := strings.Index(texts[j].data, n.Data); k >= 0 {
+				src.texts[n] = textSpan{span: texts[j].span, skip: k}
+				*next = j + 1
+				break
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		src.trace(c, tags, texts, next)
+	}
+}
+
+// scanTag finds the attributes in raw, a start tag at offset off, the way
+// html.Tokenizer reads them.
+func scanTag(off int, raw string) *tagSpan {
+	tag := &tagSpan{span: span{off: off, raw: raw}}
+	isSpace := func(c byte) bool {
+		return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+	}
+
+	i := 1
+	for i < len(raw) && !isSpace(raw[i]) && raw[i] != '/' && raw[i] != '>' {
+		i++
+	}
+	for i < len(raw) {
+		for i < len(raw) && (isSpace(raw[i]) || raw[i] == '/') {
+			i++
+		}
+		if i >= len(raw) || raw[i] == '>' {
+			break
+		}
+
+		start := i
+		i++
+		for i < len(raw) && !isSpace(raw[i]) && raw[i] != '/' && raw[i] != '>' && raw[i] != '=' {
+			i++
+		}
+		attr := attrSpan{key: strings.ToLower(raw[start:i]), off: off + start}
+
+		j := i
+		for j < len(raw) && isSpace(raw[j]) {
+			j++
+		}
+		if j < len(raw) && raw[j] == '=' {
+			i = j + 1
+			for i < len(raw) && isSpace(raw[i]) {
+				i++
+			}
+			if i < len(raw) && (raw[i] == '"' || raw[i] == '\'') {
+				quote := raw[i]
+				i++
+				end := strings.IndexByte(raw[i:], quote)
+				if end < 0 {
+					end = len(raw) - i
+				}
+				attr.val = span{off: off + i, raw: raw[i : i+end]}
+				i += end + 1
+			} else {
+				start := i
+				for i < len(raw) && !isSpace(raw[i]) && raw[i] != '>' {
+					i++
+				}
+				attr.val = span{off: off + start, raw: raw[start:i]}
+			}
+		} else {
+			attr.val = span{off: attr.off}
+		}
+		tag.attrs = append(tag.attrs, attr)
+	}
+	return tag
+}
+
+func normalizeNewlines(data []byte) []byte {
+	if !bytes.ContainsRune(data, '\r') {
+		return data
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+}
+
+func (src *source) pos(off int) token.Pos {
+	if src == nil || off < 0 || off > src.file.Size() {
+		return token.NoPos
+	}
+	return src.file.Pos(off)
+}
+
+func (src *source) position(p token.Pos) token.Position {
+	if src == nil || !p.IsValid() {
+		return token.Position{}
+	}
+	return src.file.Position(p)
+}
+
+// copyNode returns a detached copy of n that shares n's children and
+// position.
+func (src *source) copyNode(n *html.Node) *html.Node {
+	c := &html.Node{
+		FirstChild: n.FirstChild,
+		LastChild:  n.LastChild,
+		Type:       n.Type,
+		DataAtom:   n.DataAtom,
+		Data:       n.Data,
+		Namespace:  n.Namespace,
+		Attr:       n.Attr,
+	}
+	if src != nil {
+		src.copies[c] = n
+	}
+	return c
+}
+
+func (src *source) original(n *html.Node) *html.Node {
+	for {
+		orig, ok := src.copies[n]
+		if !ok {
+			return n
+		}
+		n = orig
+	}
+}
+
+// srcRange is the part of a file from pos up to but not including end.
+type srcRange struct {
+	pos, end token.Pos
+}
+
+func point(p token.Pos) srcRange {
+	return srcRange{pos: p, end: p}
+}
+
+func (src *source) spanRange(off, end int) srcRange {
+	return srcRange{pos: src.pos(off), end: src.pos(end)}
+}
+
+// nodeRange returns the range of n's start tag or text, or that of its
+// nearest ancestor with a known position.
+func (src *source) nodeRange(n *html.Node) srcRange {
+	if src == nil {
+		return srcRange{}
+	}
+	for ; n != nil; n = n.Parent {
+		n = src.original(n)
+		if tag, ok := src.tags[n]; ok {
+			return src.spanRange(tag.off, tag.off+len(tag.raw))
+		}
+		if text, ok := src.texts[n]; ok {
+			return src.spanRange(text.off+rawOffset(text.raw, text.skip), text.off+rawOffset(text.raw, text.skip+len(n.Data)))
+		}
+	}
+	return srcRange{}
+}
+
+// textPos returns the position of byte i of the text node n's data.
+func (src *source) textPos(n *html.Node, i int) token.Pos {
+	if src == nil {
+		return token.NoPos
+	}
+	if text, ok := src.texts[src.original(n)]; ok {
+		return src.pos(text.off + rawOffset(text.raw, text.skip+i))
+	}
+	return src.nodeRange(n).pos
+}
+
+func (src *source) attr(n *html.Node, key string) (attrSpan, bool) {
+	if src == nil {
+		return attrSpan{}, false
+	}
+	if tag, ok := src.tags[src.original(n)]; ok {
+		for _, attr := range tag.attrs {
+			if attr.key == key {
+				return attr, true
+			}
+		}
+	}
+	return attrSpan{}, false
+}
+
+// attrRange returns the range of the attribute key on n, or of n's start tag
+// when the attribute was not written in it.
+func (src *source) attrRange(n *html.Node, key string) srcRange {
+	if attr, ok := src.attr(n, key); ok {
+		return src.spanRange(attr.off, attr.val.off+len(attr.val.raw))
+	}
+	return src.nodeRange(n)
+}
+
+// attrValRange returns the range of the value of the attribute key on n.
+func (src *source) attrValRange(n *html.Node, key string) srcRange {
+	if attr, ok := src.attr(n, key); ok {
+		return src.spanRange(attr.val.off, attr.val.off+len(attr.val.raw))
+	}
+	return src.nodeRange(n)
+}
+
+// attrValPos returns the position of byte i of the value of the attribute
+// key on n.
+func (src *source) attrValPos(n *html.Node, key string, i int) token.Pos {
+	if attr, ok := src.attr(n, key); ok {
+		return src.pos(attr.val.off + rawOffset(attr.val.raw, i))
+	}
+	return src.nodeRange(n).pos
+}
+
+// setScript records where text, the body of the <script type="text/tmplx">,
+// starts in the file.
+func (src *source) setScript(text *html.Node) {
+	if src == nil {
+		return
+	}
+	src.scriptOff = -1
+	if p := src.textPos(text, 0); p.IsValid() {
+		src.scriptOff = src.file.Offset(p)
+	}
+}
+
+// scriptPos converts p, a position in the Go file parsed from the
+// <script type="text/tmplx">, to a position in the file.
+func (src *source) scriptPos(p token.Pos) token.Pos {
+	if src == nil || src.script == nil || !p.IsValid() {
+		return token.NoPos
+	}
+	return src.scriptOffsetPos(src.script.Offset(p))
+}
+
+// scriptRange returns the range of n, a node of the Go file parsed from the
+// <script type="text/tmplx">.
+func (src *source) scriptRange(n ast.Node) srcRange {
+	return srcRange{pos: src.scriptPos(n.Pos()), end: src.scriptPos(n.End())}
+}
+
+// scriptOffsetPos is scriptPos for an offset in the parsed Go file.
+func (src *source) scriptOffsetPos(off int) token.Pos {
+	if src == nil || src.scriptOff < 0 {
+		return token.NoPos
+	}
+	return src.pos(src.scriptOff + max(off-len(scriptPrefix), 0))
+}
+
+// rawOffset returns the offset in raw, text as written in the source, of
+// byte i of its unescaped form.
+func rawOffset(raw string, i int) int {
+	if !strings.Contains(raw, "&") {
+		return min(i, len(raw))
+	}
+	for k := 0; k < len(raw); k++ {
+		if len(html.UnescapeString(raw[:k])) >= i {
+			return k
+		}
+	}
+	return len(raw)
+}
+
+// syntaxError splits a go/parser error into the offset of its first problem
+// in the parsed source and the message without the position.
+func syntaxError(err error) (int, string) {
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		return list[0].Pos.Offset, list[0].Msg
+	}
+	return 0, err.Error()
+}
@@ -0,0 +1,149 @@
+package compiler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// stateApp is a page in a layout with a component, each with state.
+var stateApp = fstest.MapFS{
+	"pages/_layout.html": {Data: []byte(`<script type="text/tmplx">
+var theme string = "light"
+</script>
+<html><head></head><body data-theme="{ theme }"><slot></slot></body></html>
+`)},
+	"pages/index.html": {Data: []byte(`<script type="text/tmplx">
+var price int = 100
+var charged int
+
+func charge() {
+	charged = price
+}
+</script>
+<p>charged { charged }</p>
+<button tx-onclick="charge()">charge</button>
+<tx-counter></tx-counter>
+`)},
+	"components/counter.html": {Data: []byte(`<script type="text/tmplx">
+var n int = 5
+
+func inc() {
+	n++
+}
+</script>
+<span>n { n }</span>
+<button tx-onclick="inc()">inc</button>
+`)},
+}
+
+// stateAppTest runs events on stateApp with an entry left out of the state
+// they send back.
+const stateAppTest = `package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const (
+	pageEvent = "/tx/%2F%7B$%7D:charge"
+	compEvent = "/tx/tx-counter:inc"
+	compID    = "tx-counter-1"
+)
+
+func TestMissingState(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	tests := []struct {
+		name   string
+		opts   []Option
+		route  string
+		drop   string
+		status int
+		reload bool
+	}{
+		{"all state", []Option{WithStateKeys(key)}, pageEvent, "", http.StatusOK, false},
+		{"page", []Option{WithStateKeys(key)}, pageEvent, "page", http.StatusBadRequest, false},
+		{"layout", []Option{WithStateKeys(key)}, pageEvent, "/_layout", http.StatusBadRequest, false},
+		{"component", []Option{WithStateKeys(key)}, compEvent, compID, http.StatusBadRequest, false},
+		{"page without keys", nil, pageEvent, "page", http.StatusNoContent, true},
+		{"component without keys", nil, compEvent, compID, http.StatusNoContent, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(tt.opts...)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			_, saved, _ := strings.Cut(rec.Body.String(), "id=\"tx-saved\">")
+			saved, _, _ = strings.Cut(saved, "</script>")
+			state := map[string]json.RawMessage{}
+			if err := json.Unmarshal([]byte(saved), &state); err != nil {
+				t.Fatalf("tx-saved: %v", err)
+			}
+			delete(state, tt.drop)
+			data, _ := json.Marshal(state)
+
+			form := url.Values{"tx-state": {string(data)}, "tx-swap": {compID}}
+			req := httptest.NewRequest("POST", tt.route, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if reload := rec.Header().Get("Tx-Reload") == "true"; reload != tt.reload {
+				t.Errorf("reload = %t, want %t", reload, tt.reload)
+			}
+			if tt.status == http.StatusOK && !strings.Contains(rec.Body.String(), "charged 100") {
+				t.Errorf("event did not run on the page state: %s", rec.Body)
+			}
+		})
+	}
+}
+`
+
+func TestMissingState(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and tests a generated app")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	// The compiler runs the go command in the module of the app.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module app\n\ngo 1.25\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	res, err := Compile(Config{
+		PagesDirs:      []string{"pages"},
+		ComponentsDirs: []string{"components"},
+		OutputFile:     filepath.Join(dir, "routes.go"),
+	}, stateApp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"routes.go":     string(res.Source),
+		"state_test.go": stateAppTest,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test: %v\n%s", err, out)
+	}
+}
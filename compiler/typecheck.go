@@ -0,0 +1,201 @@
+package compiler
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// goNames turns the names generated code gives to a component's variables
+// back into the names written in the component.
+var goNames = strings.NewReplacer("tx_saved.S_", "", "tx_derived_", "")
+
+// typeCheck type-checks the output package, with files in place of the
+// files on disk. Errors in code marked by userCode are reported against the
+// page or component it was copied from.
+func (c *Compiler) typeCheck(files []outputFile) *multiError {
+	merr := newMultiError()
+	outputDir, err := filepath.Abs(c.outputDir())
+	if err != nil {
+		merr.append(Diagnostic{Code: CodeTypeError, Message: fmt.Sprintf("type check: %v", err)})
+		return merr
+	}
+
+	// Generated files left over from an earlier build would declare the
+	// same functions again until the new files are written.
+	overlay := map[string][]byte{}
+	entries, _ := os.ReadDir(outputDir)
+	for _, entry := range entries {
+		name := filepath.Join(outputDir, entry.Name())
+		if filepath.Ext(name) != ".go" || entry.IsDir() {
+			continue
+		}
+		if src, err := os.ReadFile(name); err == nil && IsGenerated(src) {
+			overlay[name] = []byte("package " + c.cfg.PackageName + "\n")
+		}
+	}
+	for _, file := range files {
+		name := filepath.Join(outputDir, file.name)
+		src, err := checkSource(name, file)
+		if err != nil {
+			merr.append(Diagnostic{Code: CodeTypeError, Message: fmt.Sprintf("type check: %v", err)})
+			return merr
+		}
+		overlay[name] = src
+	}
+
+	dir := existingDir(outputDir)
+	rel, err := filepath.Rel(dir, outputDir)
+	if err != nil {
+		merr.append(Diagnostic{Code: CodeTypeError, Message: fmt.Sprintf("type check: %v", err)})
+		return merr
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     dir,
+		Overlay: overlay,
+	}, "./"+filepath.ToSlash(rel))
+	if err != nil {
+		merr.append(Diagnostic{Code: CodeTypeError, Message: fmt.Sprintf("type check: %v", err)})
+		return merr
+	}
+
+	// Line directives name files relative to the output directory.
+	filePaths := map[string]string{}
+	for _, comp := range c.sources() {
+		file := filepath.FromSlash(comp.lineFile)
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(outputDir, file)
+		}
+		filePaths[file] = comp.FilePath
+	}
+
+	// Errors in code that was not copied from a page or component are
+	// usually caused by ones that were, so they are only reported on their
+	// own. So are the errors of the go command, which also compiles the
+	// package. Its errors in files written by hand, such as an unused import
+	// in a file being edited, do not stop the output from being written and
+	// are only warnings.
+	var inFiles, inGenerated, other []Diagnostic
+	seen := map[string]struct{}{}
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			if e.Kind != packages.ListError {
+				continue
+			}
+			d := Diagnostic{Code: CodeTypeError, Message: fmt.Sprintf("type check: %s", e.Msg)}
+			if handWritten(overlay, dir, e) {
+				d.Severity = SeverityWarning
+				merr.append(d)
+				continue
+			}
+			other = append(other, d)
+		}
+		for _, e := range pkg.TypeErrors {
+			if _, ok := overlay[e.Fset.PositionFor(e.Pos, false).Filename]; !ok {
+				continue
+			}
+
+			msg := goNames.Replace(e.Msg)
+			pos := e.Fset.PositionFor(e.Pos, true)
+			file, ok := filePaths[pos.Filename]
+			if !ok {
+				inGenerated = append(inGenerated, Diagnostic{Code: CodeTypeError, Message: fmt.Sprintf("in generated code: %s", msg)})
+				continue
+			}
+			p := Position{Line: pos.Line, Column: pos.Column}
+			d := Diagnostic{
+				File:    file,
+				Range:   Range{Start: p, End: p},
+				Code:    CodeTypeError,
+				Message: msg,
+			}
+			if _, ok := seen[d.Error()]; !ok {
+				seen[d.Error()] = struct{}{}
+				inFiles = append(inFiles, d)
+			}
+		}
+	}
+	for _, diags := range [][]Diagnostic{inFiles, inGenerated, other} {
+		if len(diags) > 0 {
+			for _, d := range diags {
+				merr.append(d)
+			}
+			break
+		}
+	}
+	return merr
+}
+
+// errorFile matches the file of each error the go command prints.
+var errorFile = regexp.MustCompile(`(?m)^(\S+):\d+:\d+: `)
+
+// handWritten reports whether the error e of the go command, run in dir, is
+// only in Go files that are not in overlay, or in no file. Errors in code
+// copied from a page or component name its .html file.
+func handWritten(overlay map[string][]byte, dir string, e packages.Error) bool {
+	var files []string
+	if file, _, ok := strings.Cut(e.Pos, ":"); ok {
+		files = append(files, file)
+	}
+	for _, m := range errorFile.FindAllStringSubmatch(e.Msg, -1) {
+		files = append(files, m[1])
+	}
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if _, ok := overlay[file]; ok || filepath.Ext(file) != ".go" {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSource returns the source the type checker reads for file, at path
+// name. It keeps the formatted package clause and imports, which goimports
+// completed, and follows them with the unformatted declarations, where each
+// mark becomes a line directive. The mark after each piece of copied code
+// points back into the file.
+func checkSource(name string, file outputFile) ([]byte, error) {
+	header, err := parser.ParseFile(token.NewFileSet(), name, file.source, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	headerEnd := header.Name.End()
+	if len(header.Decls) > 0 {
+		headerEnd = header.Decls[len(header.Decls)-1].End()
+	}
+	var src strings.Builder
+	src.Write(file.source[:int(headerEnd)-1])
+	src.WriteString("\n")
+	src.WriteString(userCodeMark.ReplaceAllStringFunc(file.decls, func(mark string) string {
+		pos := strings.Trim(mark, "\x00")
+		if pos == "" {
+			pos = name + ":1:1"
+		}
+		return "/*line " + pos + "*/"
+	}))
+	return []byte(src.String()), nil
+}
+
+// existingDir returns dir, or its closest ancestor that exists when dir does
+// not exist yet because nothing was written to it.
+func existingDir(dir string) string {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// configFileName is the project config file, read from the module root.
+const configFileName = "tmplx.json"
+
+// projectConfig is the content of tmplx.json. Each key sets the default of
+// the flag with the same name, so flags given on the command line take
+// priority. Relative paths are relative to the module root.
+type projectConfig struct {
+	PagesDir      stringList
+	ComponentsDir stringList
+	OutputFile    *string
+	OutputDir     *string
+	PackageName   *string
+	HandlerPrefix *string
+	StateEncoding *string
+	Diagnostics   *string
+	TypeCheck     *bool
+}
+
+// keys maps each key of the config file to the field it is decoded into.
+func (cfg *projectConfig) keys() map[string]any {
+	return map[string]any{
+		"pages-dir":      &cfg.PagesDir,
+		"components-dir": &cfg.ComponentsDir,
+		"output-file":    &cfg.OutputFile,
+		"output-dir":     &cfg.OutputDir,
+		"package-name":   &cfg.PackageName,
+		"handler-prefix": &cfg.HandlerPrefix,
+		"state-encoding": &cfg.StateEncoding,
+		"diagnostics":    &cfg.Diagnostics,
+		"type-check":     &cfg.TypeCheck,
+	}
+}
+
+// stringList is a list of strings that may be written as a single string.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = stringList{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("must be a string or a list of strings")
+	}
+	*l = list
+	return nil
+}
+
+// loadConfig applies the config file at filePath, if there is one, to the
+// flags not in set.
+func loadConfig(filePath string, set map[string]bool) error {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	var cfg projectConfig
+	keys := cfg.keys()
+	for _, key := range slices.Sorted(maps.Keys(raw)) {
+		field, ok := keys[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown key %q (available: %s)", filePath, key, strings.Join(slices.Sorted(maps.Keys(keys)), ", "))
+		}
+		if err := json.Unmarshal(raw[key], field); err != nil {
+			return fmt.Errorf("%s: %s: %w", filePath, key, err)
+		}
+	}
+
+	if cfg.PagesDir != nil && !set["pages-dir"] {
+		if len(cfg.PagesDir) == 0 {
+			return fmt.Errorf("%s: pages-dir must list at least one directory", filePath)
+		}
+		pagesDirs = configPaths(cfg.PagesDir)
+	}
+	if cfg.ComponentsDir != nil && !set["components-dir"] {
+		componentsDirs = configPaths(cfg.ComponentsDir)
+	}
+	if !set["output-file"] && !set["output-dir"] {
+		if cfg.OutputFile != nil && cfg.OutputDir != nil {
+			return fmt.Errorf("%s: output-file and output-dir cannot be used together", filePath)
+		}
+		if cfg.OutputFile != nil {
+			outputFilePath = configPaths([]string{*cfg.OutputFile})[0]
+		}
+		if cfg.OutputDir != nil {
+			outputDirPath = configPaths([]string{*cfg.OutputDir})[0]
+		}
+	}
+	if cfg.PackageName != nil && !set["package-name"] {
+		outputPackageName = *cfg.PackageName
+	}
+	if cfg.HandlerPrefix != nil && !set["handler-prefix"] {
+		outputEventHandlerPrefix = *cfg.HandlerPrefix
+	}
+	if cfg.StateEncoding != nil && !set["state-encoding"] {
+		stateEncoding = *cfg.StateEncoding
+	}
+	if cfg.Diagnostics != nil && !set["diagnostics"] {
+		diagnosticsFormat = *cfg.Diagnostics
+	}
+	if cfg.TypeCheck != nil && !set["type-check"] {
+		typeCheck = *cfg.TypeCheck
+	}
+	return nil
+}
+
+// configPaths resolves the slash-separated paths of the config file against
+// the module root.
+func configPaths(paths []string) []string {
+	resolved := make([]string, len(paths))
+	for i, p := range paths {
+		p = filepath.FromSlash(p)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(moduleRoot, p)
+		}
+		resolved[i] = filepath.Clean(p)
+	}
+	return resolved
+}
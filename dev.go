@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//go:embed dev.js
+var devScript string
+
+const devEventsPath = "/_tmplx/events"
+
+type devEvent struct {
+	name string
+	data string
+}
+
+// devServer compiles, builds and runs the app, and proxies it so that every
+// HTML page gets a live-reload client next to the tx-runtime script.
+type devServer struct {
+	builder  *builder
+	appDir   string
+	binPath  string
+	appStale bool
+
+	mu      sync.Mutex
+	errs    []string
+	clients map[chan devEvent]struct{}
+
+	appMu  sync.Mutex
+	app    *exec.Cmd
+	exited chan struct{}
+}
+
+func dev() error {
+	tmpDir, err := os.MkdirTemp("", "tmplx-dev-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	d := &devServer{
+		builder: newBuilder(),
+		appDir:  appDir(),
+		binPath: filepath.Join(tmpDir, "app"),
+		clients: map[chan devEvent]struct{}{},
+	}
+	defer d.stopApp()
+
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: devAppAddr})
+	proxy.ModifyResponse = injectDevScript
+	proxy.ErrorHandler = d.serveUnavailable
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(devEventsPath, d.serveEvents)
+	mux.Handle("/", proxy)
+
+	ln, err := net.Listen("tcp", devAddr)
+	if err != nil {
+		return err
+	}
+	go http.Serve(ln, mux)
+	log.Printf("dev server listening on http://%s (proxying %s)\n", ln.Addr(), devAppAddr)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d.reload(d.builder.build(), true)
+
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- watchFiles(append([]string{d.appDir}, sourceDirs()...), d.onChange)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-watchErr:
+		return err
+	}
+}
+
+// appDir returns the directory of the generated package, which dev builds
+// and runs as the app.
+func appDir() string {
+	if outputDirPath != "" {
+		return outputDirPath
+	}
+	return filepath.Dir(outputFilePath)
+}
+
+func (d *devServer) onChange(paths []string) {
+	build := false
+	for _, p := range paths {
+		if isOutput(p) {
+			continue
+		}
+		switch filepath.Base(p) {
+		case "go.mod", "go.sum":
+			build = true
+		}
+		if filepath.Ext(p) == ".go" {
+			build = true
+		}
+	}
+
+	err := d.builder.rebuild(sourcePaths(paths))
+	d.reload(err, build || d.builder.changed)
+}
+
+// reload shows err in the browsers, or rebuilds and restarts the app when
+// build is set (or the last go build failed) and then reloads the browsers.
+func (d *devServer) reload(err error, build bool) {
+	if err != nil {
+		printErrors(err)
+		d.setErrors(errorList(err))
+		return
+	}
+
+	if build || d.appStale {
+		if errs := d.buildApp(); len(errs) > 0 {
+			d.appStale = true
+			printErrors(errors.Join(errs...))
+			d.setErrors(errs)
+			return
+		}
+		d.appStale = false
+
+		if err := d.startApp(); err != nil {
+			log.Printf("error: start app: %v\n", err)
+			d.setErrors([]error{fmt.Errorf("start app: %w", err)})
+			return
+		}
+		d.waitForApp()
+	}
+
+	d.setErrors(nil)
+	d.broadcast(devEvent{name: "reload"})
+}
+
+func (d *devServer) buildApp() []error {
+	cmd := exec.Command("go", "build", "-o", d.binPath, ".")
+	cmd.Dir = d.appDir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	errs := []error{}
+	for line := range strings.Lines(string(out)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		errs = append(errs, errors.New(line))
+	}
+	if len(errs) == 0 {
+		errs = append(errs, fmt.Errorf("go build: %w", err))
+	}
+	return errs
+}
+
+func (d *devServer) startApp() error {
+	d.stopApp()
+
+	d.appMu.Lock()
+	defer d.appMu.Unlock()
+
+	cmd := exec.Command(d.binPath)
+	cmd.Dir = d.appDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	d.app = cmd
+	d.exited = exited
+	return nil
+}
+
+func (d *devServer) stopApp() {
+	d.appMu.Lock()
+	defer d.appMu.Unlock()
+	if d.app == nil {
+		return
+	}
+
+	d.app.Process.Signal(os.Interrupt)
+	select {
+	case <-d.exited:
+	case <-time.After(3 * time.Second):
+		d.app.Process.Kill()
+		<-d.exited
+	}
+	d.app = nil
+}
+
+// waitForApp blocks until the app accepts connections, exits, or takes too
+// long to start, so browsers do not reload into a refused connection.
+func (d *devServer) waitForApp() {
+	d.appMu.Lock()
+	exited := d.exited
+	d.appMu.Unlock()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.DialTimeout("tcp", devAppAddr, 100*time.Millisecond); err == nil {
+			conn.Close()
+			return
+		}
+		select {
+		case <-exited:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (d *devServer) setErrors(errs []error) {
+	msgs := []string{}
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	d.mu.Lock()
+	d.errs = msgs
+	d.mu.Unlock()
+
+	if len(msgs) > 0 {
+		data, _ := json.Marshal(msgs)
+		d.broadcast(devEvent{name: "errors", data: string(data)})
+	}
+}
+
+func (d *devServer) broadcast(event devEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for client := range d.clients {
+		select {
+		case client <- event:
+		default:
+		}
+	}
+}
+
+func (d *devServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	client := make(chan devEvent, 8)
+	d.mu.Lock()
+	d.clients[client] = struct{}{}
+	errs := slices.Clone(d.errs)
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.clients, client)
+		d.mu.Unlock()
+	}()
+
+	if len(errs) > 0 {
+		data, _ := json.Marshal(errs)
+		fmt.Fprintf(w, "event: errors\ndata: %s\n\n", data)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-client:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, event.data)
+			flusher.Flush()
+		}
+	}
+}
+
+// serveUnavailable stands in for the app while it is down or failed to
+// build, so the tab keeps the live-reload client instead of going dead.
+func (d *devServer) serveUnavailable(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusBadGateway)
+	fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>tmplx dev</title>%s</head><body><p>waiting for the app at %s: %s</p></body></html>", devScriptTag(), html.EscapeString(devAppAddr), html.EscapeString(err.Error()))
+}
+
+func injectDevScript(res *http.Response) error {
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") || res.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if i := bytes.Index(body, []byte(`id="tx-runtime"`)); i >= 0 {
+		if j := bytes.Index(body[i:], []byte("</script>")); j >= 0 {
+			at := i + j + len("</script>")
+			body = slices.Concat(body[:at], []byte(devScriptTag()), body[at:])
+		}
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+func devScriptTag() string {
+	return "<script id=\"tx-dev\">" + strings.Replace(devScript, "TX_DEV_EVENTS", devEventsPath, 1) + "</script>"
+}
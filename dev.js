@@ -0,0 +1,35 @@
+(function() {
+  const overlayId = "tx-dev-overlay"
+
+  const hide = () => {
+    document.getElementById(overlayId)?.remove()
+  }
+
+  const show = (errors) => {
+    hide()
+    const overlay = document.createElement("div")
+    overlay.id = overlayId
+    overlay.style.cssText = "position:fixed;inset:0;z-index:2147483647;overflow:auto;padding:2rem;background:rgba(24,24,27,.95);color:#fafafa;font:14px/1.5 ui-monospace,monospace"
+
+    const title = document.createElement("div")
+    title.style.cssText = "margin-bottom:1rem;color:#f87171;font-weight:bold"
+    title.textContent = errors.length + " error(s)"
+    overlay.appendChild(title)
+
+    for (const err of errors) {
+      const pre = document.createElement("pre")
+      pre.style.cssText = "margin:0 0 .75rem;white-space:pre-wrap"
+      pre.textContent = err
+      overlay.appendChild(pre)
+    }
+    document.body.appendChild(overlay)
+  }
+
+  const source = new EventSource("TX_DEV_EVENTS")
+  source.addEventListener("reload", () => location.reload())
+  source.addEventListener("errors", (e) => {
+    const render = () => show(JSON.parse(e.data))
+    if (document.body) render()
+    else document.addEventListener("DOMContentLoaded", render)
+  })
+})();
@@ -3,6 +3,7 @@ module github.com/gnituy18/tmplx
 go 1.25.0
 
 require (
+	github.com/fsnotify/fsnotify v1.9.0
 	golang.org/x/net v0.52.0
 	golang.org/x/text v0.36.0
 	golang.org/x/tools v0.43.0
@@ -11,4 +12,5 @@ require (
 require (
 	golang.org/x/mod v0.34.0 // indirect
 	golang.org/x/sync v0.20.0 // indirect
+	golang.org/x/sys v0.42.0 // indirect
 )
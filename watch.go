@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce batches the burst of events an editor emits for one save.
+const watchDebounce = 100 * time.Millisecond
+
+func watch() error {
+	b := newBuilder()
+	if err := b.build(); err != nil {
+		printErrors(err)
+	}
+	log.Printf("watching %s for changes\n", strings.Join(sourceDirs(), ", "))
+
+	return watchFiles(sourceDirs(), func(paths []string) {
+		if err := b.rebuild(sourcePaths(paths)); err != nil {
+			printErrors(err)
+		}
+	})
+}
+
+// watchFiles calls onChange with the paths touched since the last call,
+// once changes under roots settle. Directories created later are watched
+// too; hidden directories such as .git are skipped.
+func watchFiles(roots []string, onChange func(paths []string)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for _, dir := range roots {
+		if exist, err := dirExist(dir); err != nil {
+			return err
+		} else if !exist {
+			continue
+		}
+		if err := watchDirs(watcher, dir); err != nil {
+			return err
+		}
+	}
+
+	pending := map[string]struct{}{}
+	var debounce <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if event.Has(fsnotify.Create) {
+				if exist, _ := dirExist(event.Name); exist {
+					if err := watchDirs(watcher, event.Name); err != nil {
+						log.Printf("error: %s: cannot watch: %v\n", event.Name, err)
+					}
+				}
+			}
+
+			pending[event.Name] = struct{}{}
+			debounce = time.After(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("error: watch: %v\n", err)
+
+		case <-debounce:
+			paths := slices.Sorted(maps.Keys(pending))
+			clear(pending)
+			debounce = nil
+
+			onChange(paths)
+		}
+	}
+}
+
+func watchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// sourcePaths keeps the paths that can affect the compiled output: HTML
+// files under a pages or components directory, directories, and anything
+// that no longer exists (a removed directory takes its files with it).
+func sourcePaths(paths []string) []string {
+	return slices.DeleteFunc(slices.Clone(paths), func(p string) bool {
+		if !slices.ContainsFunc(sourceDirs(), func(dir string) bool { return isUnder(p, dir) }) {
+			return true
+		}
+		if filepath.Ext(p) == ".html" {
+			return false
+		}
+		info, err := os.Stat(p)
+		return err == nil && !info.IsDir()
+	})
+}
+
+func isUnder(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))
+}